@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ListIpamPoolsForVpc maps each of the VPC's CIDRs that was allocated by an IPAM visible to this account to
+// the ID of the pool it came from.  IPAM usually lives in a delegated administrator account, so a lookup
+// failure is not treated as an error; the map is simply empty.
+func ListIpamPoolsForVpc(ctx context.Context, sess *session.Session, vpcID string) map[string]string {
+	svc := ec2.New(sess)
+	pools := map[string]string{}
+
+	ipams, err := svc.DescribeIpamsWithContext(ctx, &ec2.DescribeIpamsInput{})
+	if err != nil {
+		if debugFlag {
+			fmt.Printf("Unable to describe IPAMs, IPAM allocations will not be reported: %v\n", err)
+		}
+		return pools
+	}
+
+	for _, ipam := range ipams.Ipams {
+		for _, scopeID := range []*string{ipam.PrivateDefaultScopeId, ipam.PublicDefaultScopeId} {
+			if scopeID == nil {
+				continue
+			}
+			result, err := svc.GetIpamResourceCidrsWithContext(ctx, &ec2.GetIpamResourceCidrsInput{
+				IpamScopeId: scopeID,
+				ResourceId:  aws.String(vpcID),
+			})
+			if err != nil {
+				if debugFlag {
+					fmt.Printf("Unable to list IPAM resource CIDRs in scope %s: %v\n", aws.StringValue(scopeID), err)
+				}
+				continue
+			}
+			for _, cidr := range result.IpamResourceCidrs {
+				if cidr.IpamPoolId != nil {
+					pools[aws.StringValue(cidr.ResourceCidr)] = aws.StringValue(cidr.IpamPoolId)
+				}
+			}
+		}
+	}
+
+	return pools
+}
+
+// DisassociateVpcCidrBlocks disassociates the secondary IPv4 CIDR blocks and all IPv6 CIDR blocks from the VPC,
+// reporting where each block came from.  It must run after the subnets using those blocks have been deleted.
+// The primary IPv4 CIDR cannot be disassociated; it goes away with the VPC.  Every block is attempted; the
+// failures are collected and returned together.
+func DisassociateVpcCidrBlocks(ctx context.Context, sess *session.Session, vpc *ec2.Vpc, ipamPools map[string]string) error {
+	fmt.Println("Disassociating VPC CIDR blocks...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, assoc := range vpc.CidrBlockAssociationSet {
+		cidr := aws.StringValue(assoc.CidrBlock)
+		if cidr == aws.StringValue(vpc.CidrBlock) || !isCidrAssociated(assoc.CidrBlockState) {
+			continue
+		}
+
+		fmt.Printf("Disassociating secondary IPv4 CIDR %s (%s) from VPC %s...\n",
+			cidr, aws.StringValue(assoc.AssociationId), aws.StringValue(vpc.VpcId))
+		err := disassociateVpcCidrBlock(ctx, ec2Svc, cidr, assoc.AssociationId, ipamPools)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, assoc := range vpc.Ipv6CidrBlockAssociationSet {
+		cidr := aws.StringValue(assoc.Ipv6CidrBlock)
+		if !isCidrAssociated(assoc.Ipv6CidrBlockState) {
+			continue
+		}
+
+		source := "Amazon-provided"
+		if pool := aws.StringValue(assoc.Ipv6Pool); pool != "" && pool != "Amazon" {
+			source = "BYOIP pool " + pool
+		}
+		fmt.Printf("Disassociating %s IPv6 CIDR %s (%s) from VPC %s...\n",
+			source, cidr, aws.StringValue(assoc.AssociationId), aws.StringValue(vpc.VpcId))
+		err := disassociateVpcCidrBlock(ctx, ec2Svc, cidr, assoc.AssociationId, ipamPools)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("VPC CIDR blocks disassociated.")
+	return nil
+}
+
+// disassociateVpcCidrBlock disassociates a single CIDR block association, honoring the --force flag, and reports
+// the IPAM release once the disassociation succeeded.
+func disassociateVpcCidrBlock(ctx context.Context, ec2Svc *ec2.EC2, cidr string, associationID *string, ipamPools map[string]string) error {
+	return forceAction("disassociate", "CIDR block", cidr, func() error {
+		_, err := ec2Svc.DisassociateVpcCidrBlockWithContext(ctx, &ec2.DisassociateVpcCidrBlockInput{
+			AssociationId: associationID,
+		})
+		if err != nil {
+			return err
+		}
+		reportIpamRelease(cidr, ipamPools)
+		return nil
+	})
+}
+
+// reportIpamRelease prints a line when the CIDR was allocated from an IPAM pool, since disassociating
+// (or deleting the VPC) returns the allocation to that pool.  It is only called after the call succeeded.
+func reportIpamRelease(cidr string, ipamPools map[string]string) {
+	if pool, ok := ipamPools[cidr]; ok {
+		fmt.Printf("Released CIDR %s back to IPAM pool %s.\n", cidr, pool)
+	}
+}
+
+// isCidrAssociated reports whether a VPC CIDR block association is still in effect.
+func isCidrAssociated(state *ec2.VpcCidrBlockState) bool {
+	if state == nil {
+		return false
+	}
+	switch aws.StringValue(state.State) {
+	case ec2.VpcCidrBlockStateCodeAssociated, ec2.VpcCidrBlockStateCodeAssociating:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"time"
+)
+
+// clientVpnDisassociateTimeout bounds how long we wait for Client VPN target networks to disassociate.
+const clientVpnDisassociateTimeout = 15 * time.Minute
+
+// ClientVpnEndpointTargets is a Client VPN endpoint together with its target network associations in one VPC.
+type ClientVpnEndpointTargets struct {
+	Endpoint *ec2.ClientVpnEndpoint
+	Targets  []*ec2.TargetNetwork
+}
+
+// ListClientVpnEndpointsForVpc lists the Client VPN endpoints that belong to the VPC or have target networks in it.
+func ListClientVpnEndpointsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ClientVpnEndpointTargets, error) {
+	svc := ec2.New(sess)
+
+	var all []*ec2.ClientVpnEndpoint
+	err := svc.DescribeClientVpnEndpointsPagesWithContext(ctx, &ec2.DescribeClientVpnEndpointsInput{},
+		func(page *ec2.DescribeClientVpnEndpointsOutput, lastPage bool) bool {
+			all = append(all, page.ClientVpnEndpoints...)
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Client VPN endpoints for VPC %s: %v", vpcID, err)
+	}
+
+	var endpoints []*ClientVpnEndpointTargets
+	for _, endpoint := range all {
+		targets, err := listClientVpnTargetNetworksInVpc(ctx, svc, endpoint.ClientVpnEndpointId, vpcID)
+		if err != nil {
+			return nil, err
+		}
+		if len(targets) > 0 || aws.StringValue(endpoint.VpcId) == vpcID {
+			endpoints = append(endpoints, &ClientVpnEndpointTargets{Endpoint: endpoint, Targets: targets})
+		}
+	}
+	return endpoints, nil
+}
+
+// listClientVpnTargetNetworksInVpc lists the endpoint's target network associations that are in the VPC and not yet disassociated.
+func listClientVpnTargetNetworksInVpc(ctx context.Context, svc *ec2.EC2, endpointID *string, vpcID string) ([]*ec2.TargetNetwork, error) {
+	all, err := listClientVpnTargetNetworks(ctx, svc, endpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target networks for Client VPN endpoint %s: %v", aws.StringValue(endpointID), err)
+	}
+
+	var targets []*ec2.TargetNetwork
+	for _, target := range all {
+		if aws.StringValue(target.VpcId) != vpcID {
+			continue
+		}
+		if target.Status != nil && aws.StringValue(target.Status.Code) == ec2.AssociationStatusCodeDisassociated {
+			continue
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// listClientVpnTargetNetworks lists all of the endpoint's target network associations, page by page.
+func listClientVpnTargetNetworks(ctx context.Context, svc *ec2.EC2, endpointID *string) ([]*ec2.TargetNetwork, error) {
+	var targets []*ec2.TargetNetwork
+	err := svc.DescribeClientVpnTargetNetworksPagesWithContext(ctx, &ec2.DescribeClientVpnTargetNetworksInput{
+		ClientVpnEndpointId: endpointID,
+	}, func(page *ec2.DescribeClientVpnTargetNetworksOutput, lastPage bool) bool {
+		targets = append(targets, page.ClientVpnTargetNetworks...)
+		return true
+	})
+	return targets, err
+}
+
+// DisassociateClientVpnTargetNetworks disassociates each endpoint's target networks in the VPC and waits for
+// the disassociations to complete.  Each association is billed hourly and holds an ENI in its subnet.  Every
+// endpoint is attempted; the failures are collected and returned together.
+func DisassociateClientVpnTargetNetworks(ctx context.Context, sess *session.Session, vpcID string, endpoints []*ClientVpnEndpointTargets) error {
+	fmt.Println("Disassociating Client VPN target networks...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, endpoint := range endpoints {
+		endpointID := endpoint.Endpoint.ClientVpnEndpointId
+		if len(endpoint.Targets) == 0 {
+			continue
+		}
+
+		failed := false
+		for _, target := range endpoint.Targets {
+			fmt.Printf("Disassociating subnet %s from Client VPN endpoint %s...\n",
+				aws.StringValue(target.TargetNetworkId), aws.StringValue(endpointID))
+			err := forceAction("disassociate", "Client VPN target network", aws.StringValue(target.AssociationId), func() error {
+				_, err := ec2Svc.DisassociateClientVpnTargetNetworkWithContext(ctx, &ec2.DisassociateClientVpnTargetNetworkInput{
+					ClientVpnEndpointId: endpointID,
+					AssociationId:       target.AssociationId,
+				})
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Error disassociating Client VPN target network: %v\n", err)
+				errs = append(errs, err)
+				failed = true
+			}
+		}
+
+		if !forceFlag || failed {
+			continue
+		}
+
+		fmt.Printf("Waiting for Client VPN endpoint %s target networks to be disassociated...\n", aws.StringValue(endpointID))
+		err := waitForClientVpnTargetNetworksDisassociated(ctx, ec2Svc, endpointID, vpcID)
+		if err != nil {
+			results.Record("wait for", "Client VPN target networks "+aws.StringValue(endpointID), err)
+			errs = append(errs, err)
+			continue
+		}
+		endpoint.Targets = nil
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Client VPN target networks disassociated.")
+	return nil
+}
+
+// waitForClientVpnTargetNetworksDisassociated polls the endpoint until none of its target networks in the VPC
+// remain associated.
+func waitForClientVpnTargetNetworksDisassociated(ctx context.Context, ec2Svc *ec2.EC2, endpointID *string, vpcID string) error {
+	deadline := time.Now().Add(clientVpnDisassociateTimeout)
+	for {
+		remaining, err := listClientVpnTargetNetworksInVpc(ctx, ec2Svc, endpointID, vpcID)
+		if err != nil {
+			return err
+		}
+		if len(remaining) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %d target networks of Client VPN endpoint %s to be disassociated",
+				len(remaining), aws.StringValue(endpointID))
+		}
+		if err := sleepContext(ctx, 15*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// DeleteClientVpnEndpoints deletes the Client VPN endpoints that have no target network associations left.
+// Every endpoint is attempted; the failures are collected and returned together.
+func DeleteClientVpnEndpoints(ctx context.Context, sess *session.Session, endpoints []*ClientVpnEndpointTargets) error {
+	fmt.Println("Deleting Client VPN endpoints...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, endpoint := range endpoints {
+		endpointID := endpoint.Endpoint.ClientVpnEndpointId
+
+		targets, err := listClientVpnTargetNetworks(ctx, ec2Svc, endpointID)
+		if err != nil {
+			results.Record("list", "Client VPN target networks "+aws.StringValue(endpointID), err)
+			errs = append(errs, err)
+			continue
+		}
+		associated := 0
+		for _, target := range targets {
+			if target.Status == nil || aws.StringValue(target.Status.Code) != ec2.AssociationStatusCodeDisassociated {
+				associated++
+			}
+		}
+		if associated > 0 {
+			fmt.Printf("Skipping Client VPN endpoint %s, it still has %d target network associations...\n",
+				aws.StringValue(endpointID), associated)
+			continue
+		}
+
+		fmt.Printf("Deleting Client VPN endpoint %s (%s)...\n", aws.StringValue(endpointID), getNameTag(endpoint.Endpoint.Tags))
+		err = forceAction("delete", "Client VPN endpoint", aws.StringValue(endpointID), func() error {
+			_, err := ec2Svc.DeleteClientVpnEndpointWithContext(ctx, &ec2.DeleteClientVpnEndpointInput{
+				ClientVpnEndpointId: endpointID,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting Client VPN endpoint: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Client VPN endpoints deleted.")
+	return nil
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Target is a named selection of what to work on, defined under "targets" in the config file.  The same keys
+// at the top level of the config file are the defaults for every run.
+type Target struct {
+	Profiles             []string `mapstructure:"profiles"`
+	Regions              []string `mapstructure:"regions"`
+	Accounts             []string `mapstructure:"accounts"`
+	Tags                 []string `mapstructure:"tags"`
+	ResourceTypes        []string `mapstructure:"resource-types"`
+	ExcludeResourceTypes []string `mapstructure:"exclude-resource-types"`
+}
+
+var (
+	configFile string
+	targetName string
+
+	// accountAllowlist, when not empty, limits the run to these account IDs.
+	accountAllowlist []string
+	// tagFilters, when not empty, limits the run to VPCs carrying all of these "key" or "key=value" tags.
+	tagFilters []string
+	// resourceTypes, when not empty, limits deletion to these resource types.
+	resourceTypes []string
+	// excludeResourceTypes are resource types that are not deleted.
+	excludeResourceTypes []string
+)
+
+// readConfig reads the config file named by --config, or ~/.aws-vpc-nuke.yaml when it exists.  Environment
+// variables named after a config key take precedence over the file.
+func readConfig() error {
+	viper.AutomaticEnv()
+
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path := filepath.Join(home, ".aws-vpc-nuke.yaml")
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+		viper.SetConfigFile(path)
+	}
+
+	err := viper.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", viper.ConfigFileUsed(), err)
+	}
+	if debugFlag {
+		fmt.Println("Using config file", viper.ConfigFileUsed())
+	}
+	return nil
+}
+
+// loadTarget returns the top-level settings of the config file, overridden by those of the named target.
+func loadTarget(name string) (Target, error) {
+	var target Target
+	err := viper.Unmarshal(&target)
+	if err != nil {
+		return target, fmt.Errorf("failed to parse config file %s: %v", viper.ConfigFileUsed(), err)
+	}
+	if name == "" {
+		return target, nil
+	}
+
+	sub := viper.Sub("targets." + name)
+	if sub == nil {
+		return target, fmt.Errorf("target %q is not defined in the config file", name)
+	}
+	var named Target
+	err = sub.Unmarshal(&named)
+	if err != nil {
+		return target, fmt.Errorf("failed to parse target %q: %v", name, err)
+	}
+	if named.Profiles != nil {
+		target.Profiles = named.Profiles
+	}
+	if named.Regions != nil {
+		target.Regions = named.Regions
+	}
+	if named.Accounts != nil {
+		target.Accounts = named.Accounts
+	}
+	if named.Tags != nil {
+		target.Tags = named.Tags
+	}
+	if named.ResourceTypes != nil {
+		target.ResourceTypes = named.ResourceTypes
+	}
+	if named.ExcludeResourceTypes != nil {
+		target.ExcludeResourceTypes = named.ExcludeResourceTypes
+	}
+	return target, nil
+}
+
+// applyConfig settles the settings for the run.  Each one comes from, in order of precedence: the flag when it is
+// given on the command line, the --target, the top level of the config file, and finally the flag's default.
+func applyConfig(cmd *cobra.Command) error {
+	err := readConfig()
+	if err != nil {
+		return err
+	}
+	if targetName != "" && viper.ConfigFileUsed() == "" {
+		return errors.New("--target needs a config file: use --config or create ~/.aws-vpc-nuke.yaml")
+	}
+	target, err := loadTarget(targetName)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	settings := []struct {
+		flag   string
+		value  *[]string
+		config []string
+	}{
+		{"profile-list", &profileList, target.Profiles},
+		{"region-list", &regionList, target.Regions},
+		{"accounts", &accountAllowlist, target.Accounts},
+		{"tags", &tagFilters, target.Tags},
+		{"resource-types", &resourceTypes, target.ResourceTypes},
+		{"exclude-resource-types", &excludeResourceTypes, target.ExcludeResourceTypes},
+	}
+	for _, setting := range settings {
+		if flag := flags.Lookup(setting.flag); flag != nil && flag.Changed {
+			continue
+		}
+		if setting.config != nil {
+			*setting.value = setting.config
+		}
+	}
+
+	if debugFlag {
+		fmt.Printf("Profiles: %v, regions: %v, accounts: %v, tags: %v, resource types: %v, excluded resource types: %v\n",
+			profileList, regionList, accountAllowlist, tagFilters, resourceTypes, excludeResourceTypes)
+	}
+	return nil
+}
+
+// AccountAllowed reports whether the account may be worked on.  Every account is allowed when there is no
+// allowlist.
+func AccountAllowed(account string) bool {
+	if len(accountAllowlist) == 0 {
+		return true
+	}
+	for _, allowed := range accountAllowlist {
+		if allowed == account {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectVpcs returns the VPCs that carry every tag in tagFilters.  A filter is either "key", which matches any
+// value, or "key=value".
+func SelectVpcs(vpcs []*ec2.Vpc) []*ec2.Vpc {
+	if len(tagFilters) == 0 {
+		return vpcs
+	}
+
+	var selected []*ec2.Vpc
+	for _, vpc := range vpcs {
+		if matchesTagFilters(vpc.Tags, tagFilters) {
+			selected = append(selected, vpc)
+		}
+	}
+	return selected
+}
+
+// matchesTagFilters reports whether tags satisfy every filter.
+func matchesTagFilters(tags []*ec2.Tag, filters []string) bool {
+	for _, filter := range filters {
+		key, value, hasValue := strings.Cut(filter, "=")
+		found := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == key && (!hasValue || aws.StringValue(tag.Value) == value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testConfig = `
+profiles: [default-profile]
+regions: [us-east-1]
+tags: [team]
+targets:
+  staging:
+    profiles: [staging]
+    regions: [eu-west-1, eu-west-2]
+  sandbox:
+    tags: [env=sandbox]
+`
+
+// newConfigTestCommand returns a command with the settings flags of the root command, bound to the same variables.
+func newConfigTestCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringSliceVarP(&regionList, "region-list", "r", []string{"us-west-2"}, "")
+	cmd.Flags().StringSliceVarP(&profileList, "profile-list", "p", []string{""}, "")
+	cmd.Flags().StringVar(&configFile, "config", "", "")
+	cmd.Flags().StringVarP(&targetName, "target", "t", "", "")
+	cmd.Flags().StringSliceVar(&accountAllowlist, "accounts", nil, "")
+	cmd.Flags().StringSliceVar(&tagFilters, "tags", nil, "")
+	return cmd
+}
+
+// restoreConfig puts back the settings that applyConfig writes, and resets viper, when the test ends.
+func restoreConfig(t *testing.T) {
+	profiles, regions, accounts, tags := profileList, regionList, accountAllowlist, tagFilters
+	types, excludedTypes := resourceTypes, excludeResourceTypes
+	file, target := configFile, targetName
+	t.Cleanup(func() {
+		profileList, regionList, accountAllowlist, tagFilters = profiles, regions, accounts, tags
+		resourceTypes, excludeResourceTypes = types, excludedTypes
+		configFile, targetName = file, target
+		viper.Reset()
+	})
+}
+
+func TestApplyConfig(t *testing.T) {
+	restoreConfig(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		profiles []string
+		regions  []string
+		tags     []string
+		wantErr  bool
+	}{
+		{
+			name:     "top level of the config file",
+			args:     []string{"--config", path},
+			profiles: []string{"default-profile"},
+			regions:  []string{"us-east-1"},
+			tags:     []string{"team"},
+		},
+		{
+			name:     "target overrides the top level",
+			args:     []string{"--config", path, "--target", "staging"},
+			profiles: []string{"staging"},
+			regions:  []string{"eu-west-1", "eu-west-2"},
+			tags:     []string{"team"},
+		},
+		{
+			name:     "target keeps the top level for keys it does not set",
+			args:     []string{"--config", path, "--target", "sandbox"},
+			profiles: []string{"default-profile"},
+			regions:  []string{"us-east-1"},
+			tags:     []string{"env=sandbox"},
+		},
+		{
+			name:     "flags override the target",
+			args:     []string{"--config", path, "--target", "staging", "-r", "ap-south-1", "--tags", "owner=me"},
+			profiles: []string{"staging"},
+			regions:  []string{"ap-south-1"},
+			tags:     []string{"owner=me"},
+		},
+		{
+			name:    "unknown target",
+			args:    []string{"--config", path, "--target", "production"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			cmd := newConfigTestCommand()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applyConfig(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(profileList, tt.profiles) {
+				t.Errorf("profiles = %v, want %v", profileList, tt.profiles)
+			}
+			if !reflect.DeepEqual(regionList, tt.regions) {
+				t.Errorf("regions = %v, want %v", regionList, tt.regions)
+			}
+			if !reflect.DeepEqual(tagFilters, tt.tags) {
+				t.Errorf("tags = %v, want %v", tagFilters, tt.tags)
+			}
+		})
+	}
+}
+
+func TestMatchesTagFilters(t *testing.T) {
+	tags := []*ec2.Tag{
+		{Key: aws.String("env"), Value: aws.String("sandbox")},
+		{Key: aws.String("team"), Value: aws.String("")},
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		want    bool
+	}{
+		{"no filters", nil, true},
+		{"key matches any value", []string{"env"}, true},
+		{"key matches an empty value", []string{"team"}, true},
+		{"key and value match", []string{"env=sandbox"}, true},
+		{"value differs", []string{"env=prod"}, false},
+		{"empty value only matches an empty value", []string{"env="}, false},
+		{"explicit empty value", []string{"team="}, true},
+		{"missing key", []string{"owner"}, false},
+		{"every filter must match", []string{"env=sandbox", "owner"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesTagFilters(tags, tt.filters); got != tt.want {
+				t.Errorf("matchesTagFilters(%v) = %v, want %v", tt.filters, got, tt.want)
+			}
+		})
+	}
+}
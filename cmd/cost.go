@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/spf13/cobra"
+)
+
+var priceFile string
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Estimate the daily cost of the billable VPC resources",
+	Long: "Estimate the daily cost of the billable VPC resources: NAT gateways, interface and Gateway Load Balancer " +
+		"endpoints, public IPv4 addresses and transit gateway attachments, rolled up per VPC, region, account and in " +
+		"total. Prices come from a built-in table that --price-file can override",
+	RunE: costFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+
+	costCmd.Flags().StringVar(&priceFile, "price-file", "", "JSON file with prices that override the built-in price table")
+}
+
+func costFunc(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	prices, err := LoadPriceTable(priceFile)
+	if err != nil {
+		return err
+	}
+
+	var items []CostItem
+	err = IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
+			// Use the current profile and region to create a new session.
+			sess, err := GetSession(profile, region)
+			if err != nil {
+				results.SetScope(profile, region)
+				results.Record("create", "session", err)
+				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
+			}
+			account := AccountLabel(ctx, sess, profile)
+			if !AccountAllowed(account) {
+				fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, account)
+				return nil
+			}
+			results.SetScope(account, region)
+
+			vpcs, err := ListVpcs(ctx, sess)
+			if err != nil {
+				results.Record("list", "VPCs", err)
+				return fmt.Errorf("failed to list VPC resources: %v", err)
+			}
+
+			// A VPC that cannot be priced is reported and left out; the other VPCs are still priced.
+			var errs MultiError
+			for _, vpc := range SelectVpcs(vpcs) {
+				vpcID := aws.StringValue(vpc.VpcId)
+				vpcItems, err := ListCostItemsForVpc(ctx, sess, account, region, vpcID, prices.For(region))
+				if err != nil {
+					results.Record("price", "VPC "+vpcID, err)
+					errs = append(errs, fmt.Errorf("failed to price VPC %s: %v", vpcID, err))
+					continue
+				}
+				items = append(items, vpcItems...)
+			}
+
+			eipItems, err := ListUnassociatedEipCostItems(ctx, sess, account, region, prices.For(region))
+			if err != nil {
+				results.Record("list", "Elastic IPs", err)
+				errs = append(errs, err)
+			}
+			items = append(items, eipItems...)
+
+			return errs.ErrorOrNil()
+		})
+	})
+
+	// Report what was priced even when some regions failed.
+	PrintCostReport(items)
+	if err != nil {
+		return fmt.Errorf("failed to price VPC resources: %v", err)
+	}
+	return nil
+}
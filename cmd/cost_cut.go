@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/spf13/cobra"
+)
+
+var keepIdleEips bool
+
+var costCutCmd = &cobra.Command{
+	Use:   "cost-cut",
+	Short: "Delete only the hourly-billed VPC resources and keep the network topology",
+	Long: "Delete only the VPC resources that are billed by the hour: NAT gateways and their Elastic IPs, interface " +
+		"endpoints, Client VPN target network associations, transit gateway attachments and VPN connections. " +
+		"VPCs, subnets, route tables, security groups and gateways are kept so the VPCs can be re-used. " +
+		"Every idle Elastic IP in the region is released as well, unless --keep-idle-eips is given",
+	RunE: costCutFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(costCutCmd)
+
+	costCutCmd.Flags().BoolVar(&keepIdleEips, "keep-idle-eips", false, "Release only the Elastic IPs of the deleted NAT gateways, not every idle Elastic IP in the region")
+}
+
+func costCutFunc(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
+			fmt.Printf("Cutting costs in %s (%s)\n", profile, region)
+
+			// Use the current profile and region to create a new session.
+			sess, err := GetSession(profile, region)
+			if err != nil {
+				results.SetScope(profile, region)
+				results.Record("create", "session", err)
+				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
+			}
+			account := AccountLabel(ctx, sess, profile)
+			if !AccountAllowed(account) {
+				fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, account)
+				return nil
+			}
+			results.SetScope(account, region)
+
+			vpcs, err := ListVpcs(ctx, sess)
+			if err != nil {
+				results.Record("list", "VPCs", err)
+				return fmt.Errorf("failed to list VPCs: %v", err)
+			}
+
+			var errs MultiError
+			var natAllocationIDs []string
+			for _, vpc := range SelectVpcs(vpcs) {
+				results.SetVpc(aws.StringValue(vpc.VpcId))
+				ids, err := CostCutVpc(ctx, sess, vpc)
+				natAllocationIDs = append(natAllocationIDs, ids...)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("VPC %s: %v", aws.StringValue(vpc.VpcId), err))
+				}
+			}
+			results.SetVpc("")
+
+			// Elastic IPs are not tied to a VPC; release the idle ones once the NAT gateways have let go of theirs.
+			eips, err := ListIdleEips(ctx, sess, natAllocationIDs, !keepIdleEips)
+			if err != nil {
+				results.Record("list", "Elastic IPs", err)
+				return fmt.Errorf("failed to list idle Elastic IPs: %v", err)
+			}
+			if len(eips) > 0 {
+				fmt.Printf("Releasing %d idle Elastic IPs in %s (%s)...\n", len(eips), profile, region)
+				err := ReleaseEips(ctx, sess, eips)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to release idle Elastic IPs: %v", err))
+				}
+			}
+
+			return errs.ErrorOrNil()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to IterateOverProfiles: %v", err)
+	}
+	return nil
+}
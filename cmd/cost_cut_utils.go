@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// hourlyBilledEndpoints returns the interface and Gateway Load Balancer endpoints, which are billed per hour.
+// Gateway endpoints (S3, DynamoDB) are free and are left alone.
+func hourlyBilledEndpoints(vpcEndpoints []*ec2.VpcEndpoint) []*ec2.VpcEndpoint {
+	var billed []*ec2.VpcEndpoint
+	for _, vpcEndpoint := range vpcEndpoints {
+		switch aws.StringValue(vpcEndpoint.VpcEndpointType) {
+		case ec2.VpcEndpointTypeInterface, ec2.VpcEndpointTypeGatewayLoadBalancer:
+		default:
+			continue
+		}
+		switch aws.StringValue(vpcEndpoint.State) {
+		case "deleting", "deleted", "rejected", "failed":
+			continue
+		}
+		billed = append(billed, vpcEndpoint)
+	}
+	return billed
+}
+
+// activeNatGateways returns the NAT gateways that are still billed, leaving out deleted and failed ones.
+func activeNatGateways(natGateways []*ec2.NatGateway) []*ec2.NatGateway {
+	var active []*ec2.NatGateway
+	for _, natGw := range natGateways {
+		switch aws.StringValue(natGw.State) {
+		case ec2.NatGatewayStatePending, ec2.NatGatewayStateAvailable:
+			active = append(active, natGw)
+		}
+	}
+	return active
+}
+
+// WaitForNatGatewaysDeleted waits until the specified NAT gateways are deleted, which is when their Elastic IPs
+// are disassociated.
+func WaitForNatGatewaysDeleted(ctx context.Context, sess *session.Session, natGateways []*ec2.NatGateway) error {
+	if !forceFlag || len(natGateways) == 0 {
+		return nil
+	}
+
+	var ids []*string
+	for _, natGw := range natGateways {
+		ids = append(ids, natGw.NatGatewayId)
+	}
+	fmt.Println("Waiting for NAT gateways to be deleted...")
+	err := ec2.New(sess).WaitUntilNatGatewayDeletedWithContext(ctx, &ec2.DescribeNatGatewaysInput{
+		NatGatewayIds: ids,
+	})
+	if err != nil {
+		results.Record("wait for", "NAT gateways", err)
+	}
+	return err
+}
+
+// CostCutVpc removes the hourly-billed resources of the VPC: NAT gateways, interface endpoints, Client VPN target
+// network associations, transit gateway attachments and VPN connections.  Subnets, route tables, security groups
+// and the rest of the topology stay in place.  It returns the allocation IDs of the Elastic IPs that the deleted
+// NAT gateways used.
+func CostCutVpc(ctx context.Context, sess *session.Session, vpc *ec2.Vpc) ([]string, error) {
+	vpcID := aws.StringValue(vpc.VpcId)
+	fmt.Println("Cutting costs in VPC", vpcID)
+
+	// With --ignore-errors, failed steps are collected and returned once every step has been attempted.
+	var errs MultiError
+	var natAllocationIDs []string
+	natGateways, err := ListNatGatewaysForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "NAT gateways", err)
+		fmt.Printf("failed to list NAT gateways for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return nil, err
+		}
+	}
+
+	natGateways = activeNatGateways(natGateways)
+	if len(natGateways) > 0 {
+		fmt.Printf("Deleting %d NAT gateways in VPC %s...\n", len(natGateways), vpcID)
+		deleted, err := DeleteNatGateways(ctx, sess, natGateways)
+		// The Elastic IPs of every gateway that was deleted are up for release, even if another one failed;
+		// ListIdleEips leaves out any that a gateway still holds.
+		for _, natGw := range deleted {
+			for _, address := range natGw.NatGatewayAddresses {
+				if address.AllocationId != nil {
+					natAllocationIDs = append(natAllocationIDs, aws.StringValue(address.AllocationId))
+				}
+			}
+		}
+		if waitErr := WaitForNatGatewaysDeleted(ctx, sess, deleted); err == nil {
+			err = waitErr
+		}
+		if err != nil {
+			fmt.Printf("failed to delete NAT gateways for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return natAllocationIDs, err
+			}
+		}
+	}
+
+	vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "VPC endpoints", err)
+		fmt.Printf("failed to list VPC endpoints for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return natAllocationIDs, err
+		}
+	}
+
+	vpcEndpoints = hourlyBilledEndpoints(vpcEndpoints)
+	if len(vpcEndpoints) > 0 {
+		fmt.Printf("Deleting %d interface endpoints in VPC %s...\n", len(vpcEndpoints), vpcID)
+		err := DeleteVpcEndpoints(ctx, sess, vpcEndpoints)
+		if err != nil {
+			fmt.Printf("failed to delete interface endpoints for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return natAllocationIDs, err
+			}
+		}
+	}
+
+	// The Client VPN endpoint itself is kept; each target network association is billed per hour.
+	clientVpnEndpoints, err := ListClientVpnEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "Client VPN endpoints", err)
+		fmt.Printf("failed to list Client VPN endpoints for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return natAllocationIDs, err
+		}
+	}
+
+	if len(clientVpnEndpoints) > 0 {
+		fmt.Printf("Disassociating %d Client VPN endpoints from VPC %s...\n", len(clientVpnEndpoints), vpcID)
+		err := DisassociateClientVpnTargetNetworks(ctx, sess, vpcID, clientVpnEndpoints)
+		if err != nil {
+			fmt.Printf("failed to disassociate Client VPN target networks for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return natAllocationIDs, err
+			}
+		}
+	}
+
+	tgwAttachments, err := ListTransitGatewayVpcAttachmentsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "transit gateway attachments", err)
+		fmt.Printf("failed to list transit gateway attachments for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return natAllocationIDs, err
+		}
+	}
+
+	if len(tgwAttachments) > 0 {
+		fmt.Printf("Deleting %d transit gateway attachments in VPC %s...\n", len(tgwAttachments), vpcID)
+		err := DeleteTransitGatewayVpcAttachments(ctx, sess, tgwAttachments)
+		if err != nil {
+			fmt.Printf("failed to delete transit gateway attachments for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return natAllocationIDs, err
+			}
+		}
+	}
+
+	vpnConnections, err := ListVpnConnectionsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "VPN connections", err)
+		fmt.Printf("failed to list VPN connections for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return natAllocationIDs, err
+		}
+	}
+
+	if len(vpnConnections) > 0 {
+		fmt.Printf("Deleting %d VPN connections in VPC %s...\n", len(vpnConnections), vpcID)
+		err := DeleteVpnConnections(ctx, sess, vpnConnections)
+		if err != nil {
+			fmt.Printf("failed to delete VPN connections for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return natAllocationIDs, err
+			}
+		}
+	}
+
+	return natAllocationIDs, errs.ErrorOrNil()
+}
+
+// ListIdleEips lists the Elastic IPs that are billed without doing anything: those not associated with anything,
+// and those associated with a network interface that is not attached to anything or no longer exists.  Only the addresses in
+// natAllocationIDs, which a deleted NAT gateway just let go of, are listed, unless all is set; then every idle
+// address in the region is, narrowed by --tags.
+func ListIdleEips(ctx context.Context, sess *session.Session, natAllocationIDs []string, all bool) ([]*ec2.Address, error) {
+	svc := ec2.New(sess)
+
+	result, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []*string{aws.String("vpc")},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Elastic IPs: %v", err)
+	}
+
+	fromNat := map[string]bool{}
+	for _, id := range natAllocationIDs {
+		fromNat[id] = true
+	}
+
+	var candidates []*ec2.Address
+	var eniIDs []*string
+	for _, eip := range result.Addresses {
+		if !fromNat[aws.StringValue(eip.AllocationId)] && (!all || !matchesTagFilters(eip.Tags, tagFilters)) {
+			continue
+		}
+		if eip.AssociationId != nil && eip.NetworkInterfaceId == nil {
+			continue
+		}
+		candidates = append(candidates, eip)
+		if eip.AssociationId != nil {
+			eniIDs = append(eniIDs, eip.NetworkInterfaceId)
+		}
+	}
+
+	// Filtering rather than asking for the IDs tolerates network interfaces that are gone by now; an address
+	// whose interface is gone is idle too.  A filter takes at most 200 values.
+	attached := map[string]bool{}
+	for start := 0; start < len(eniIDs); start += 200 {
+		end := start + 200
+		if end > len(eniIDs) {
+			end = len(eniIDs)
+		}
+		input := &ec2.DescribeNetworkInterfacesInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("network-interface-id"),
+					Values: eniIDs[start:end],
+				},
+			},
+		}
+		err := svc.DescribeNetworkInterfacesPagesWithContext(ctx, input, func(page *ec2.DescribeNetworkInterfacesOutput, lastPage bool) bool {
+			for _, eni := range page.NetworkInterfaces {
+				if aws.StringValue(eni.Status) != ec2.NetworkInterfaceStatusAvailable {
+					attached[aws.StringValue(eni.NetworkInterfaceId)] = true
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list network interfaces of Elastic IPs: %v", err)
+		}
+	}
+
+	var idle []*ec2.Address
+	for _, eip := range candidates {
+		if eip.AssociationId == nil || !attached[aws.StringValue(eip.NetworkInterfaceId)] {
+			idle = append(idle, eip)
+		}
+	}
+	return idle, nil
+}
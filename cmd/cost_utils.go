@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sort"
+)
+
+// Kinds of billable resources in the cost report.
+const (
+	CostNatGateway                  = "nat-gateway"
+	CostInterfaceEndpoint           = "interface-endpoint"
+	CostGatewayLoadBalancerEndpoint = "gwlb-endpoint"
+	CostPublicIpv4                  = "public-ipv4"
+	CostTransitGatewayAttachment    = "tgw-attachment"
+)
+
+// CostItem is the estimated cost of one billable resource.  Units is the number of billed units, such as the
+// availability zones of an interface endpoint.
+type CostItem struct {
+	Account    string
+	Region     string
+	VpcID      string
+	Kind       string
+	ResourceID string
+	Units      float64
+	HourlyRate float64
+}
+
+// Daily returns the estimated cost of the item per day.
+func (i CostItem) Daily() float64 {
+	return i.Units * i.HourlyRate * 24
+}
+
+// ListCostItemsForVpc prices the billable resources of the VPC: NAT gateways, interface endpoints per
+// availability zone, Gateway Load Balancer endpoints, public IPv4 addresses on the VPC's network interfaces, and transit gateway attachments.
+// Data processing and transfer charges depend on traffic and are not included.
+func ListCostItemsForVpc(ctx context.Context, sess *session.Session, account, region, vpcID string, prices RegionPrices) ([]CostItem, error) {
+	var items []CostItem
+	item := func(kind, resourceID string, units, rate float64) {
+		items = append(items, CostItem{
+			Account:    account,
+			Region:     region,
+			VpcID:      vpcID,
+			Kind:       kind,
+			ResourceID: resourceID,
+			Units:      units,
+			HourlyRate: rate,
+		})
+	}
+
+	natGateways, err := ListNatGatewaysForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, natGw := range activeNatGateways(natGateways) {
+		item(CostNatGateway, aws.StringValue(natGw.NatGatewayId), 1, prices.NatGatewayHour)
+	}
+
+	vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, vpcEndpoint := range hourlyBilledEndpoints(vpcEndpoints) {
+		// A Gateway Load Balancer endpoint sits in a single subnet and has a price of its own.
+		if aws.StringValue(vpcEndpoint.VpcEndpointType) == ec2.VpcEndpointTypeGatewayLoadBalancer {
+			item(CostGatewayLoadBalancerEndpoint, aws.StringValue(vpcEndpoint.VpcEndpointId), 1, prices.GatewayLoadBalancerEndpointHour)
+			continue
+		}
+		// An interface endpoint is billed for every availability zone it has a subnet in.
+		azs := float64(len(vpcEndpoint.SubnetIds))
+		if azs == 0 {
+			azs = 1
+		}
+		item(CostInterfaceEndpoint, aws.StringValue(vpcEndpoint.VpcEndpointId), azs, prices.InterfaceEndpointAzHour)
+	}
+
+	// Every public IPv4 address is billed, whether it is an Elastic IP or auto-assigned, and whatever it is on.
+	enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range enis {
+		// The interface-level association is that of the primary private address; secondary private addresses
+		// can carry public addresses of their own.
+		seen := map[string]bool{}
+		var publicIps []string
+		if eni.Association != nil {
+			publicIps = append(publicIps, aws.StringValue(eni.Association.PublicIp))
+		}
+		for _, address := range eni.PrivateIpAddresses {
+			if address.Association != nil {
+				publicIps = append(publicIps, aws.StringValue(address.Association.PublicIp))
+			}
+		}
+		for _, publicIP := range publicIps {
+			if publicIP == "" || seen[publicIP] {
+				continue
+			}
+			seen[publicIP] = true
+			item(CostPublicIpv4, publicIP, 1, prices.PublicIpv4Hour)
+		}
+	}
+
+	tgwAttachments, err := ListTransitGatewayVpcAttachmentsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range tgwAttachments {
+		item(CostTransitGatewayAttachment, aws.StringValue(attachment.TransitGatewayAttachmentId), 1, prices.TransitGatewayAttachmentHour)
+	}
+
+	return items, nil
+}
+
+// ListUnassociatedEipCostItems prices the Elastic IPs that are not associated with anything, and so belong to
+// no VPC.
+func ListUnassociatedEipCostItems(ctx context.Context, sess *session.Session, account, region string, prices RegionPrices) ([]CostItem, error) {
+	svc := ec2.New(sess)
+
+	result, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []*string{aws.String("vpc")},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Elastic IPs: %v", err)
+	}
+
+	var items []CostItem
+	for _, eip := range result.Addresses {
+		if eip.AssociationId != nil || !matchesTagFilters(eip.Tags, tagFilters) {
+			continue
+		}
+		items = append(items, CostItem{
+			Account:    account,
+			Region:     region,
+			Kind:       CostPublicIpv4,
+			ResourceID: aws.StringValue(eip.PublicIp),
+			Units:      1,
+			HourlyRate: prices.PublicIpv4Hour,
+		})
+	}
+	return items, nil
+}
+
+// PrintCostReport prints every priced resource, then the estimated daily cost rolled up per VPC, per region, per
+// account and in total.
+func PrintCostReport(items []CostItem) {
+	if len(items) == 0 {
+		fmt.Println("No billable resources found.")
+		return
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.VpcID < b.VpcID
+	})
+
+	fmt.Println()
+	fmt.Println("Billable resources:")
+	fmt.Printf("%-14s %-16s %-23s %-20s %-28s %6s %10s\n", "ACCOUNT", "REGION", "VPC", "KIND", "RESOURCE", "UNITS", "USD/DAY")
+	for _, item := range items {
+		fmt.Printf("%-14s %-16s %-23s %-20s %-28s %6.0f %10.2f\n", item.Account, item.Region, item.VpcID, item.Kind, item.ResourceID, item.Units, item.Daily())
+	}
+
+	printCostRollup("VPC", items, func(item CostItem) string {
+		if item.VpcID == "" {
+			return fmt.Sprintf("%s %s (no VPC)", item.Account, item.Region)
+		}
+		return fmt.Sprintf("%s %s %s", item.Account, item.Region, item.VpcID)
+	})
+	printCostRollup("region", items, func(item CostItem) string {
+		return fmt.Sprintf("%s %s", item.Account, item.Region)
+	})
+	printCostRollup("account", items, func(item CostItem) string {
+		return item.Account
+	})
+
+	total := 0.0
+	for _, item := range items {
+		total += item.Daily()
+	}
+	fmt.Println()
+	fmt.Printf("Estimated total: %.2f USD/day (%.2f USD/month)\n", total, total*30)
+}
+
+// printCostRollup prints the daily cost of the items grouped by key.
+func printCostRollup(level string, items []CostItem, key func(CostItem) string) {
+	totals := map[string]float64{}
+	var keys []string
+	for _, item := range items {
+		k := key(item)
+		if _, ok := totals[k]; !ok {
+			keys = append(keys, k)
+		}
+		totals[k] += item.Daily()
+	}
+	sort.Strings(keys)
+
+	fmt.Println()
+	fmt.Printf("Per %s:\n", level)
+	for _, k := range keys {
+		fmt.Printf("\t%-60s %10.2f USD/day\n", k, totals[k])
+	}
+}
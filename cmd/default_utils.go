@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Rule numbers of the catch-all deny entries that every network ACL carries: the IPv4 one, and the IPv6 one
+// that is added once the VPC has an IPv6 CIDR.  They cannot be deleted.
+const (
+	catchAllNaclRuleNumber     = 32767
+	catchAllIpv6NaclRuleNumber = 32768
+)
+
+// isCatchAllNaclEntry reports whether the network ACL entry is one of the catch-all deny entries.
+func isCatchAllNaclEntry(entry *ec2.NetworkAclEntry) bool {
+	switch aws.Int64Value(entry.RuleNumber) {
+	case catchAllNaclRuleNumber, catchAllIpv6NaclRuleNumber:
+		return true
+	}
+	return false
+}
+
+// DefaultSgIDForVpc returns the ID of the VPC's default security group.
+func DefaultSgIDForVpc(ctx context.Context, sess *session.Session, vpcID string) (string, error) {
+	svc := ec2.New(sess)
+
+	result, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+			{
+				Name:   aws.String("group-name"),
+				Values: []*string{aws.String("default")},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to find the default security group of VPC %s: %v", vpcID, err)
+	}
+	if len(result.SecurityGroups) == 0 {
+		return "", fmt.Errorf("VPC %s has no default security group", vpcID)
+	}
+	return aws.StringValue(result.SecurityGroups[0].GroupId), nil
+}
+
+// isDefaultSg reports whether the security group is its VPC's default group, whose ID DefaultSgIDForVpc returned.
+func isDefaultSg(sg *ec2.SecurityGroup, defaultSgID string) bool {
+	return aws.StringValue(sg.GroupId) == defaultSgID
+}
+
+// resettingDefaults reports whether the run keeps the VPC and resets its default network ACL and security group.
+func resettingDefaults() bool {
+	return resetDefaults && (keepVpc || !typeSelected(ResourceVpc))
+}
+
+// vpcHasIpv6 reports whether the VPC has an associated IPv6 CIDR block.
+func vpcHasIpv6(vpc *ec2.Vpc) bool {
+	for _, assoc := range vpc.Ipv6CidrBlockAssociationSet {
+		if isCidrAssociated(assoc.Ipv6CidrBlockState) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResetDefaultNacl restores the VPC's default network ACL to the rules AWS creates it with: allow all inbound
+// and outbound traffic (rules 100, and 101 for IPv6), followed by the catch-all deny.
+func ResetDefaultNacl(ctx context.Context, sess *session.Session, vpc *ec2.Vpc, nacl *ec2.NetworkAcl) error {
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	fmt.Printf("Resetting default network ACL %s to its factory rules...\n", aws.StringValue(nacl.NetworkAclId))
+	return forceAction("reset", "default network ACL", aws.StringValue(nacl.NetworkAclId), func() error {
+		return resetDefaultNaclEntries(ctx, ec2Svc, vpc, nacl)
+	})
+}
+
+// resetDefaultNaclEntries replaces the entries of the default network ACL with the factory rules.
+func resetDefaultNaclEntries(ctx context.Context, ec2Svc *ec2.EC2, vpc *ec2.Vpc, nacl *ec2.NetworkAcl) error {
+	for _, entry := range nacl.Entries {
+		if isCatchAllNaclEntry(entry) {
+			continue
+		}
+		_, err := ec2Svc.DeleteNetworkAclEntryWithContext(ctx, &ec2.DeleteNetworkAclEntryInput{
+			NetworkAclId: nacl.NetworkAclId,
+			RuleNumber:   entry.RuleNumber,
+			Egress:       entry.Egress,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, egress := range []bool{false, true} {
+		_, err := ec2Svc.CreateNetworkAclEntryWithContext(ctx, &ec2.CreateNetworkAclEntryInput{
+			NetworkAclId: nacl.NetworkAclId,
+			RuleNumber:   aws.Int64(100),
+			Egress:       aws.Bool(egress),
+			Protocol:     aws.String("-1"),
+			RuleAction:   aws.String(ec2.RuleActionAllow),
+			CidrBlock:    aws.String("0.0.0.0/0"),
+		})
+		if err != nil {
+			return err
+		}
+		if vpcHasIpv6(vpc) {
+			_, err := ec2Svc.CreateNetworkAclEntryWithContext(ctx, &ec2.CreateNetworkAclEntryInput{
+				NetworkAclId:  nacl.NetworkAclId,
+				RuleNumber:    aws.Int64(101),
+				Egress:        aws.Bool(egress),
+				Protocol:      aws.String("-1"),
+				RuleAction:    aws.String(ec2.RuleActionAllow),
+				Ipv6CidrBlock: aws.String("::/0"),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResetDefaultSg restores the VPC's default security group to the rules AWS creates it with: allow all inbound
+// traffic from the group itself, and allow all outbound traffic.
+func ResetDefaultSg(ctx context.Context, sess *session.Session, vpc *ec2.Vpc, sg *ec2.SecurityGroup) error {
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	fmt.Printf("Resetting default security group %s to its factory rules...\n", aws.StringValue(sg.GroupId))
+	return forceAction("reset", "default security group", aws.StringValue(sg.GroupId), func() error {
+		return resetDefaultSgRules(ctx, ec2Svc, vpc, sg)
+	})
+}
+
+// resetDefaultSgRules replaces the rules of the default security group with the factory rules.
+func resetDefaultSgRules(ctx context.Context, ec2Svc *ec2.EC2, vpc *ec2.Vpc, sg *ec2.SecurityGroup) error {
+	// RevokeSgCrossReferences has already revoked the rules that reference groups of the VPC, the factory
+	// self-reference included, and revoking a rule that is gone fails; start from the group's current rules.
+	result, err := ec2Svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []*string{sg.GroupId},
+	})
+	if err != nil {
+		return err
+	}
+	if len(result.SecurityGroups) == 0 {
+		return fmt.Errorf("security group %s not found", aws.StringValue(sg.GroupId))
+	}
+	sg = result.SecurityGroups[0]
+
+	if len(sg.IpPermissions) > 0 {
+		_, err := ec2Svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+			GroupId:       sg.GroupId,
+			IpPermissions: sg.IpPermissions,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(sg.IpPermissionsEgress) > 0 {
+		_, err := ec2Svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+			GroupId:       sg.GroupId,
+			IpPermissions: sg.IpPermissionsEgress,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = ec2Svc.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId: sg.GroupId,
+		IpPermissions: []*ec2.IpPermission{
+			{
+				IpProtocol:       aws.String("-1"),
+				UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: sg.GroupId}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	egress := &ec2.IpPermission{
+		IpProtocol: aws.String("-1"),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	}
+	if vpcHasIpv6(vpc) {
+		egress.Ipv6Ranges = []*ec2.Ipv6Range{{CidrIpv6: aws.String("::/0")}}
+	}
+	_, err = ec2Svc.AuthorizeSecurityGroupEgressWithContext(ctx, &ec2.AuthorizeSecurityGroupEgressInput{
+		GroupId:       sg.GroupId,
+		IpPermissions: []*ec2.IpPermission{egress},
+	})
+	return err
+}
@@ -1,9 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"strings"
+	"time"
+)
+
+var (
+	includeLoadBalancers    bool
+	includeInstances        bool
+	includeManagedDeps      bool
+	includeFirewallPolicies bool
+	includeHostedZones      bool
+	disassociateRamShares   bool
+	keepVpc                 bool
+	resetDefaults           bool
+	protectionTag           string
 )
 
 var deleteCmd = &cobra.Command{
@@ -18,31 +33,75 @@ func init() {
 
 	deleteCmd.Flags().StringP("vpc-id", "v", "", "the ID of the VPC to delete")
 	viper.BindPFlag("vpc-id", deleteCmd.Flags().Lookup("vpc-id"))
+	deleteCmd.Flags().StringVar(&journalPath, "journal", "", "JSON Lines file to record every operation in, so that the run can be resumed")
+	deleteCmd.Flags().StringVar(&resumePath, "resume", "", "Journal of an interrupted run; resources it completed are skipped and the rest retried")
+	deleteCmd.Flags().StringSliceVar(&resourceTypes, "resource-types", nil, "Comma-separated list of resource types to delete (default all): "+strings.Join(allResourceTypes, ", "))
+	deleteCmd.Flags().StringSliceVar(&excludeResourceTypes, "exclude-resource-types", nil, "Comma-separated list of resource types not to delete")
+	deleteCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only select VPCs created at least this long ago, such as 72h")
+	deleteCmd.Flags().StringVar(&createdBefore, "created-before", "", "Only select VPCs created before this date (2006-01-02) or RFC 3339 time")
+	deleteCmd.Flags().StringVar(&creationTag, "creation-tag", "CreatedAt", "Tag key holding a VPC's creation time, used before the times of its NAT gateways and endpoints")
+	deleteCmd.Flags().BoolVar(&keepVpc, "keep-vpc", false, "Delete the resources inside the VPC but keep the VPC itself")
+	deleteCmd.Flags().BoolVar(&resetDefaults, "reset-defaults", false, "With --keep-vpc, reset the default network ACL and default security group to their factory rules")
+	deleteCmd.Flags().BoolVar(&includeInstances, "include-instances", false, "Also terminate EC2 instances in the VPC")
+	deleteCmd.Flags().StringVar(&protectionTag, "protection-tag", "aws-vpc-nuke:protect", "Tag key that marks instances which must never be terminated")
+	deleteCmd.Flags().BoolVar(&includeLoadBalancers, "include-load-balancers", false, "Also delete load balancers and target groups in the VPC")
+	deleteCmd.Flags().BoolVar(&includeFirewallPolicies, "include-firewall-policies", false, "Also delete the Network Firewall policies and rule groups used by deleted firewalls")
+	deleteCmd.Flags().BoolVar(&includeHostedZones, "include-hosted-zones", false, "Also delete private hosted zones whose last VPC association is the deleted VPC")
+	deleteCmd.Flags().BoolVar(&disassociateRamShares, "disassociate-ram-shares", false, "Remove the VPC's subnets from RAM resource shares before deleting them")
+	deleteCmd.Flags().BoolVar(&includeManagedDeps, "include-managed-services", false, "Also remove EFS mount targets, RDS/ElastiCache subnet groups and Lambda VPC configs that block subnet deletion")
 }
 
 func deleteFunc(cmd *cobra.Command, args []string) error {
 	fmt.Println("delete called")
 
-	regionList := viper.GetStringSlice("region-list")
-	profileList := viper.GetStringSlice("profile-list")
+	ctx := cmd.Context()
+
+	err := SetAgeCutoff(time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = OpenJournal(journalPath, resumePath)
+	if err != nil {
+		return err
+	}
+
+	selectedResourceTypes, err = SelectResourceTypes(resourceTypes, excludeResourceTypes)
+	if err != nil {
+		return err
+	}
+	WarnResourceTypeDependencies(selectedResourceTypes)
 
 	// Delete the VPC and all associated resources.
-	err := IterateOverProfiles(profileList, func(profile string) error {
-		return IterateOverRegions(regionList, func(region string) error {
+	err = IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
 			fmt.Printf("Deleting VPCs in %s (%s)\n", profile, region)
 
 			// Use the current profile and region to create a new session.
 			sess, err := GetSession(profile, region)
 			if err != nil {
+				results.SetScope(profile, region)
+				results.Record("create", "session", err)
 				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
 			}
+			account := AccountLabel(ctx, sess, profile)
+			if !AccountAllowed(account) {
+				fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, account)
+				return nil
+			}
+			results.SetScope(account, region)
+			if results.AlreadyDone(OperationComplete, "delete region") {
+				fmt.Printf("Skipping %s (%s), completed by the resumed run.\n", profile, region)
+				return nil
+			}
 
 			// Delete the VPC and all associated resources using the session.
-			err = DeleteAllVpcs(sess, forceFlag)
+			err = DeleteAllVpcs(ctx, sess, forceFlag)
 			if err != nil {
 				return fmt.Errorf("failed to delete VPC resources: %v", err)
 			}
 
+			results.Complete("delete region")
 			return nil
 		})
 	})
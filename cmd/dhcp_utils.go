@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+// ListVpcsUsingDhcpOptions lists the VPCs that are currently associated with the specified DHCP options set.
+func ListVpcsUsingDhcpOptions(ctx context.Context, sess *session.Session, dhcpOptionsID string) ([]*ec2.Vpc, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeVpcsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("dhcp-options-id"),
+				Values: []*string{aws.String(dhcpOptionsID)},
+			},
+		},
+	}
+
+	result, err := svc.DescribeVpcsWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VPCs using DHCP options set %s: %v", dhcpOptionsID, err)
+	}
+
+	return result.Vpcs, nil
+}
+
+// DeleteDhcpOptionsIfUnused deletes the specified DHCP options set, unless it is the AWS-provided
+// set for the region or it is still associated with another VPC.
+func DeleteDhcpOptionsIfUnused(ctx context.Context, sess *session.Session, dhcpOptionsID string) error {
+	if dhcpOptionsID == "" || dhcpOptionsID == "default" {
+		return nil
+	}
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	result, err := ec2Svc.DescribeDhcpOptionsWithContext(ctx, &ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: []*string{aws.String(dhcpOptionsID)},
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to describe DHCP options set %s: %v", dhcpOptionsID, err)
+		results.Record("list", "DHCP options set "+dhcpOptionsID, err)
+		return err
+	}
+	if len(result.DhcpOptions) == 0 {
+		return nil
+	}
+	if isAwsProvidedDhcpOptions(result.DhcpOptions[0]) {
+		fmt.Printf("Skipping AWS-provided DHCP options set %s...\n", dhcpOptionsID)
+		return nil
+	}
+
+	vpcs, err := ListVpcsUsingDhcpOptions(ctx, sess, dhcpOptionsID)
+	if err != nil {
+		results.Record("list", "VPCs using DHCP options set "+dhcpOptionsID, err)
+		return err
+	}
+	if len(vpcs) > 0 {
+		fmt.Printf("Skipping DHCP options set %s, still associated with %d VPC(s)...\n", dhcpOptionsID, len(vpcs))
+		return nil
+	}
+
+	fmt.Printf("Deleting DHCP options set %s (%s)...\n", dhcpOptionsID, getNameTag(result.DhcpOptions[0].Tags))
+	return forceAction("delete", "DHCP options set", dhcpOptionsID, func() error {
+		_, err := ec2Svc.DeleteDhcpOptionsWithContext(ctx, &ec2.DeleteDhcpOptionsInput{
+			DhcpOptionsId: aws.String(dhcpOptionsID),
+		})
+		return err
+	})
+}
+
+// isAwsProvidedDhcpOptions reports whether the DHCP options set looks like the one AWS creates for each region:
+// only a compute.internal domain name and AmazonProvidedDNS, with no tags.
+func isAwsProvidedDhcpOptions(options *ec2.DhcpOptions) bool {
+	if len(options.Tags) > 0 {
+		return false
+	}
+	for _, config := range options.DhcpConfigurations {
+		for _, value := range config.Values {
+			v := aws.StringValue(value.Value)
+			switch aws.StringValue(config.Key) {
+			case "domain-name":
+				if v != "ec2.internal" && !strings.HasSuffix(v, ".compute.internal") {
+					return false
+				}
+			case "domain-name-servers":
+				if v != "AmazonProvidedDNS" {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ListCarrierGatewaysForVpc lists all Wavelength carrier gateways for the specified VPC ID in the specified session.
+func ListCarrierGatewaysForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.CarrierGateway, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeCarrierGatewaysInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}
+
+	result, err := svc.DescribeCarrierGatewaysWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list carrier gateways for VPC %s: %v", vpcID, err)
+	}
+
+	return result.CarrierGateways, nil
+}
+
+// ListLocalGatewayRouteTableVpcAssociationsForVpc lists all Outposts local gateway route table associations
+// for the specified VPC ID in the specified session.
+func ListLocalGatewayRouteTableVpcAssociationsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.LocalGatewayRouteTableVpcAssociation, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeLocalGatewayRouteTableVpcAssociationsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}
+
+	result, err := svc.DescribeLocalGatewayRouteTableVpcAssociationsWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local gateway route table associations for VPC %s: %v", vpcID, err)
+	}
+
+	return result.LocalGatewayRouteTableVpcAssociations, nil
+}
+
+// ListTrafficMirrorResourcesForVpc lists the traffic mirror targets that point at an ENI, NLB or GWLB endpoint in
+// the VPC, and the traffic mirror sessions that mirror an ENI in the VPC or send to one of those targets.
+func ListTrafficMirrorResourcesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.TrafficMirrorSession, []*ec2.TrafficMirrorTarget, error) {
+	svc := ec2.New(sess)
+
+	inVpc := map[string]bool{}
+	enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, eni := range enis {
+		inVpc[aws.StringValue(eni.NetworkInterfaceId)] = true
+	}
+	lbs, err := ListLoadBalancersForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, lb := range lbs {
+		inVpc[aws.StringValue(lb.LoadBalancerArn)] = true
+	}
+	vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, vpcEndpoint := range vpcEndpoints {
+		inVpc[aws.StringValue(vpcEndpoint.VpcEndpointId)] = true
+	}
+
+	targetResult, err := svc.DescribeTrafficMirrorTargetsWithContext(ctx, &ec2.DescribeTrafficMirrorTargetsInput{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list traffic mirror targets for VPC %s: %v", vpcID, err)
+	}
+	var targets []*ec2.TrafficMirrorTarget
+	targetIDs := map[string]bool{}
+	for _, target := range targetResult.TrafficMirrorTargets {
+		if inVpc[aws.StringValue(target.NetworkInterfaceId)] ||
+			inVpc[aws.StringValue(target.NetworkLoadBalancerArn)] ||
+			inVpc[aws.StringValue(target.GatewayLoadBalancerEndpointId)] {
+			targets = append(targets, target)
+			targetIDs[aws.StringValue(target.TrafficMirrorTargetId)] = true
+		}
+	}
+
+	sessionResult, err := svc.DescribeTrafficMirrorSessionsWithContext(ctx, &ec2.DescribeTrafficMirrorSessionsInput{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list traffic mirror sessions for VPC %s: %v", vpcID, err)
+	}
+	var sessions []*ec2.TrafficMirrorSession
+	for _, mirrorSession := range sessionResult.TrafficMirrorSessions {
+		if inVpc[aws.StringValue(mirrorSession.NetworkInterfaceId)] || targetIDs[aws.StringValue(mirrorSession.TrafficMirrorTargetId)] {
+			sessions = append(sessions, mirrorSession)
+		}
+	}
+
+	return sessions, targets, nil
+}
+
+// DeleteCarrierGateways deletes the specified carrier gateways.  Routes that target them become blackholes
+// and go away with their route tables.  Every gateway is attempted; the failures are collected and returned
+// together.
+func DeleteCarrierGateways(ctx context.Context, sess *session.Session, cgws []*ec2.CarrierGateway) error {
+	fmt.Println("Deleting carrier gateways...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, cgw := range cgws {
+		fmt.Printf("Deleting carrier gateway %s (%s)...\n", aws.StringValue(cgw.CarrierGatewayId), getNameTag(cgw.Tags))
+		err := forceAction("delete", "carrier gateway", aws.StringValue(cgw.CarrierGatewayId), func() error {
+			_, err := ec2Svc.DeleteCarrierGatewayWithContext(ctx, &ec2.DeleteCarrierGatewayInput{
+				CarrierGatewayId: cgw.CarrierGatewayId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting carrier gateway: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Carrier gateways deleted.")
+	return nil
+}
+
+// DeleteLocalGatewayRouteTableVpcAssociations removes the specified local gateway route table VPC associations.
+// Every association is attempted; the failures are collected and returned together.
+func DeleteLocalGatewayRouteTableVpcAssociations(ctx context.Context, sess *session.Session, assocs []*ec2.LocalGatewayRouteTableVpcAssociation) error {
+	fmt.Println("Deleting local gateway route table associations...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, assoc := range assocs {
+		fmt.Printf("Deleting local gateway route table association %s (route table %s)...\n",
+			aws.StringValue(assoc.LocalGatewayRouteTableVpcAssociationId), aws.StringValue(assoc.LocalGatewayRouteTableId))
+		err := forceAction("delete", "local gateway route table association", aws.StringValue(assoc.LocalGatewayRouteTableVpcAssociationId), func() error {
+			_, err := ec2Svc.DeleteLocalGatewayRouteTableVpcAssociationWithContext(ctx, &ec2.DeleteLocalGatewayRouteTableVpcAssociationInput{
+				LocalGatewayRouteTableVpcAssociationId: assoc.LocalGatewayRouteTableVpcAssociationId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting local gateway route table association: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Local gateway route table associations deleted.")
+	return nil
+}
+
+// DeleteTrafficMirrorResources deletes the specified traffic mirror sessions, then the targets they used.  Every
+// session and target is attempted; the failures are collected and returned together.
+func DeleteTrafficMirrorResources(ctx context.Context, sess *session.Session, sessions []*ec2.TrafficMirrorSession, targets []*ec2.TrafficMirrorTarget) error {
+	fmt.Println("Deleting traffic mirror sessions and targets...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, mirrorSession := range sessions {
+		fmt.Printf("Deleting traffic mirror session %s (source %s)...\n",
+			aws.StringValue(mirrorSession.TrafficMirrorSessionId), aws.StringValue(mirrorSession.NetworkInterfaceId))
+		err := forceAction("delete", "traffic mirror session", aws.StringValue(mirrorSession.TrafficMirrorSessionId), func() error {
+			_, err := ec2Svc.DeleteTrafficMirrorSessionWithContext(ctx, &ec2.DeleteTrafficMirrorSessionInput{
+				TrafficMirrorSessionId: mirrorSession.TrafficMirrorSessionId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting traffic mirror session: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	for _, target := range targets {
+		fmt.Printf("Deleting traffic mirror target %s (%s)...\n",
+			aws.StringValue(target.TrafficMirrorTargetId), aws.StringValue(target.Type))
+		err := forceAction("delete", "traffic mirror target", aws.StringValue(target.TrafficMirrorTargetId), func() error {
+			_, err := ec2Svc.DeleteTrafficMirrorTargetWithContext(ctx, &ec2.DeleteTrafficMirrorTargetInput{
+				TrafficMirrorTargetId: target.TrafficMirrorTargetId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting traffic mirror target: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Traffic mirror sessions and targets deleted.")
+	return nil
+}
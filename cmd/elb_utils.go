@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"strings"
+	"time"
+)
+
+// loadBalancerEniTimeout bounds how long we wait for AWS to release load balancer network interfaces.
+const loadBalancerEniTimeout = 10 * time.Minute
+
+// ListLoadBalancersForVpc lists all application, network and gateway load balancers in the specified VPC.
+func ListLoadBalancersForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*elbv2.LoadBalancer, error) {
+	svc := elbv2.New(sess)
+
+	var lbs []*elbv2.LoadBalancer
+	err := svc.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{}, func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancers {
+			if aws.StringValue(lb.VpcId) == vpcID {
+				lbs = append(lbs, lb)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list load balancers for VPC %s: %v", vpcID, err)
+	}
+	return lbs, nil
+}
+
+// ListClassicLoadBalancersForVpc lists all Classic Load Balancers in the specified VPC.
+func ListClassicLoadBalancersForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*elb.LoadBalancerDescription, error) {
+	svc := elb.New(sess)
+
+	var lbs []*elb.LoadBalancerDescription
+	err := svc.DescribeLoadBalancersPagesWithContext(ctx, &elb.DescribeLoadBalancersInput{}, func(page *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+		for _, lb := range page.LoadBalancerDescriptions {
+			if aws.StringValue(lb.VPCId) == vpcID {
+				lbs = append(lbs, lb)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Classic Load Balancers for VPC %s: %v", vpcID, err)
+	}
+	return lbs, nil
+}
+
+// ListTargetGroupsForVpc lists all target groups in the specified VPC.
+func ListTargetGroupsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*elbv2.TargetGroup, error) {
+	svc := elbv2.New(sess)
+
+	var tgs []*elbv2.TargetGroup
+	err := svc.DescribeTargetGroupsPagesWithContext(ctx, &elbv2.DescribeTargetGroupsInput{}, func(page *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, tg := range page.TargetGroups {
+			if aws.StringValue(tg.VpcId) == vpcID {
+				tgs = append(tgs, tg)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list target groups for VPC %s: %v", vpcID, err)
+	}
+	return tgs, nil
+}
+
+// DeleteLoadBalancers deletes the specified application, network and gateway load balancers and waits for them to
+// be deleted.  It returns the network interface descriptions of the load balancers it deleted.  Every load
+// balancer is attempted; the failures are collected and returned together.
+func DeleteLoadBalancers(ctx context.Context, sess *session.Session, lbs []*elbv2.LoadBalancer) ([]string, error) {
+	fmt.Println("Deleting load balancers...")
+	// Create a new ELBv2 client using the provided session.
+	elbSvc := elbv2.New(sess)
+
+	var errs MultiError
+	var deleted []*string
+	var eniDescriptions []string
+	for _, lb := range lbs {
+		fmt.Printf("Deleting %s load balancer %s...\n", aws.StringValue(lb.Type), aws.StringValue(lb.LoadBalancerName))
+		err := forceAction("delete", "load balancer", aws.StringValue(lb.LoadBalancerName), func() error {
+			_, err := elbSvc.DeleteLoadBalancerWithContext(ctx, &elbv2.DeleteLoadBalancerInput{
+				LoadBalancerArn: lb.LoadBalancerArn,
+			})
+			if err == nil {
+				deleted = append(deleted, lb.LoadBalancerArn)
+				eniDescriptions = append(eniDescriptions, loadBalancerEniDescription(lb))
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting load balancer: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(deleted) > 0 {
+		fmt.Println("Waiting for load balancers to be deleted...")
+		err := elbSvc.WaitUntilLoadBalancersDeletedWithContext(ctx, &elbv2.DescribeLoadBalancersInput{
+			LoadBalancerArns: deleted,
+		})
+		if err != nil {
+			results.Record("wait for", "load balancers", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return eniDescriptions, errs
+	}
+	fmt.Println("Load balancers deleted.")
+	return eniDescriptions, nil
+}
+
+// DeleteClassicLoadBalancers deletes the specified Classic Load Balancers.  It returns the network interface
+// descriptions of the load balancers it deleted.  Every load balancer is attempted; the failures are collected
+// and returned together.
+func DeleteClassicLoadBalancers(ctx context.Context, sess *session.Session, lbs []*elb.LoadBalancerDescription) ([]string, error) {
+	fmt.Println("Deleting Classic Load Balancers...")
+	// Create a new ELB client using the provided session.
+	elbSvc := elb.New(sess)
+
+	var errs MultiError
+	var eniDescriptions []string
+	for _, lb := range lbs {
+		fmt.Printf("Deleting Classic Load Balancer %s...\n", aws.StringValue(lb.LoadBalancerName))
+		err := forceAction("delete", "Classic Load Balancer", aws.StringValue(lb.LoadBalancerName), func() error {
+			_, err := elbSvc.DeleteLoadBalancerWithContext(ctx, &elb.DeleteLoadBalancerInput{
+				LoadBalancerName: lb.LoadBalancerName,
+			})
+			if err == nil {
+				eniDescriptions = append(eniDescriptions, "ELB "+aws.StringValue(lb.LoadBalancerName))
+			}
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting Classic Load Balancer: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return eniDescriptions, errs
+	}
+	fmt.Println("Classic Load Balancers deleted.")
+	return eniDescriptions, nil
+}
+
+// DeleteTargetGroups deletes the specified target groups.  Target groups can only be deleted once
+// no load balancer references them, so this must run after DeleteLoadBalancers.  Every target group is
+// attempted; the failures are collected and returned together.
+func DeleteTargetGroups(ctx context.Context, sess *session.Session, tgs []*elbv2.TargetGroup) error {
+	fmt.Println("Deleting target groups...")
+	// Create a new ELBv2 client using the provided session.
+	elbSvc := elbv2.New(sess)
+
+	var errs MultiError
+	for _, tg := range tgs {
+		fmt.Printf("Deleting target group %s...\n", aws.StringValue(tg.TargetGroupName))
+		err := forceAction("delete", "target group", aws.StringValue(tg.TargetGroupName), func() error {
+			_, err := elbSvc.DeleteTargetGroupWithContext(ctx, &elbv2.DeleteTargetGroupInput{
+				TargetGroupArn: tg.TargetGroupArn,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting target group: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Target groups deleted.")
+	return nil
+}
+
+// WaitForLoadBalancerEnisReleased waits until the requester-managed network interfaces of the deleted
+// load balancers, identified by their descriptions, are gone from the VPC.  Interfaces of load balancers that
+// were not deleted are not waited for.
+func WaitForLoadBalancerEnisReleased(ctx context.Context, sess *session.Session, vpcID string, eniDescriptions []string) error {
+	if len(eniDescriptions) == 0 {
+		return nil
+	}
+	deleted := map[string]bool{}
+	for _, description := range eniDescriptions {
+		deleted[description] = true
+	}
+	fmt.Println("Waiting for load balancer network interfaces to be released...")
+	return WaitForNetworkInterfacesReleased(ctx, sess, vpcID, func(eni *ec2.NetworkInterface) bool {
+		return deleted[aws.StringValue(eni.Description)]
+	}, loadBalancerEniTimeout)
+}
+
+// loadBalancerEniDescription returns the description AWS gives the network interfaces of an ALB, NLB or GWLB:
+// "ELB " followed by the part of its ARN after "loadbalancer/", such as "ELB app/my-alb/50dc6c495c0c9188".
+// Classic Load Balancer interfaces are described as "ELB " followed by the name.
+func loadBalancerEniDescription(lb *elbv2.LoadBalancer) string {
+	arn := aws.StringValue(lb.LoadBalancerArn)
+	if i := strings.Index(arn, ":loadbalancer/"); i >= 0 {
+		return "ELB " + arn[i+len(":loadbalancer/"):]
+	}
+	return "ELB " + aws.StringValue(lb.LoadBalancerName)
+}
+
+// DeleteLoadBalancersForVpc deletes every load balancer in the VPC, then its target groups, and waits until
+// the load balancers' network interfaces have been released so that subnets and security groups can be deleted.
+func DeleteLoadBalancersForVpc(ctx context.Context, sess *session.Session, vpcID string) error {
+	lbs, err := ListLoadBalancersForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "load balancers", err)
+		return err
+	}
+	classicLbs, err := ListClassicLoadBalancersForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "Classic Load Balancers", err)
+		return err
+	}
+	tgs, err := ListTargetGroupsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "target groups", err)
+		return err
+	}
+
+	if len(lbs) == 0 && len(classicLbs) == 0 && len(tgs) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Deleting %d load balancers, %d Classic Load Balancers and %d target groups in VPC %s...\n",
+		len(lbs), len(classicLbs), len(tgs), vpcID)
+
+	var errs MultiError
+	var eniDescriptions []string
+	if len(lbs) > 0 {
+		descriptions, err := DeleteLoadBalancers(ctx, sess, lbs)
+		eniDescriptions = append(eniDescriptions, descriptions...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(classicLbs) > 0 {
+		descriptions, err := DeleteClassicLoadBalancers(ctx, sess, classicLbs)
+		eniDescriptions = append(eniDescriptions, descriptions...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(tgs) > 0 {
+		err = DeleteTargetGroups(ctx, sess, tgs)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = WaitForLoadBalancerEnisReleased(ctx, sess, vpcID, eniDescriptions)
+	if err != nil {
+		results.Record("wait for", "load balancer network interfaces", err)
+		errs = append(errs, err)
+	}
+	return errs.ErrorOrNil()
+}
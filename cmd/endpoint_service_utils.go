@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sort"
+	"strings"
+)
+
+// ListEndpointServicesForVpc lists the VPC endpoint service (PrivateLink provider) configurations that are
+// backed by a Network or Gateway Load Balancer in the specified VPC.
+func ListEndpointServicesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.ServiceConfiguration, error) {
+	svc := ec2.New(sess)
+
+	lbs, err := ListLoadBalancersForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lbs) == 0 {
+		return nil, nil
+	}
+	lbArns := map[string]bool{}
+	for _, lb := range lbs {
+		lbArns[aws.StringValue(lb.LoadBalancerArn)] = true
+	}
+
+	var configs []*ec2.ServiceConfiguration
+	err = svc.DescribeVpcEndpointServiceConfigurationsPagesWithContext(ctx, &ec2.DescribeVpcEndpointServiceConfigurationsInput{},
+		func(page *ec2.DescribeVpcEndpointServiceConfigurationsOutput, lastPage bool) bool {
+			configs = append(configs, page.ServiceConfigurations...)
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VPC endpoint services for VPC %s: %v", vpcID, err)
+	}
+
+	var services []*ec2.ServiceConfiguration
+	for _, config := range configs {
+		for _, arn := range append(config.NetworkLoadBalancerArns, config.GatewayLoadBalancerArns...) {
+			if lbArns[aws.StringValue(arn)] {
+				services = append(services, config)
+				break
+			}
+		}
+	}
+	return services, nil
+}
+
+// DeleteEndpointServices rejects the open consumer connections to each endpoint service, reporting the consumer
+// accounts that were connected, and then deletes the service configuration so that its load balancers can go.
+// Every service is attempted; the failures are collected and returned together.
+func DeleteEndpointServices(ctx context.Context, sess *session.Session, services []*ec2.ServiceConfiguration) error {
+	fmt.Println("Deleting VPC endpoint services...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, service := range services {
+		serviceID := aws.StringValue(service.ServiceId)
+
+		var connections []*ec2.VpcEndpointConnection
+		err := ec2Svc.DescribeVpcEndpointConnectionsPagesWithContext(ctx, &ec2.DescribeVpcEndpointConnectionsInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("service-id"),
+					Values: []*string{service.ServiceId},
+				},
+			},
+		}, func(page *ec2.DescribeVpcEndpointConnectionsOutput, lastPage bool) bool {
+			connections = append(connections, page.VpcEndpointConnections...)
+			return true
+		})
+		if err != nil {
+			results.Record("list", "connections of VPC endpoint service "+serviceID, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		// AWS refuses to delete a service with pending or available connections, so reject both.
+		var open []*string
+		consumers := map[string]bool{}
+		for _, conn := range connections {
+			consumers[aws.StringValue(conn.VpcEndpointOwner)] = true
+			switch aws.StringValue(conn.VpcEndpointState) {
+			case ec2.StatePendingAcceptance, ec2.StateAvailable:
+				open = append(open, conn.VpcEndpointId)
+			}
+		}
+		fmt.Printf("Endpoint service %s (%s) has %d connections from consumer accounts: %s\n",
+			serviceID, aws.StringValue(service.ServiceName), len(connections), strings.Join(sortedKeys(consumers), ", "))
+
+		if len(open) > 0 {
+			fmt.Printf("Rejecting %d open connections to endpoint service %s...\n", len(open), serviceID)
+			err := forceAction("reject", "endpoint connections of VPC endpoint service", serviceID, func() error {
+				result, err := ec2Svc.RejectVpcEndpointConnectionsWithContext(ctx, &ec2.RejectVpcEndpointConnectionsInput{
+					ServiceId:      service.ServiceId,
+					VpcEndpointIds: open,
+				})
+				if err != nil {
+					return err
+				}
+				return unsuccessfulItemsError(result.Unsuccessful)
+			})
+			if err != nil {
+				fmt.Printf("Error rejecting endpoint connections: %v\n", err)
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		fmt.Printf("Deleting endpoint service %s...\n", serviceID)
+		err = forceAction("delete", "VPC endpoint service", serviceID, func() error {
+			result, err := ec2Svc.DeleteVpcEndpointServiceConfigurationsWithContext(ctx, &ec2.DeleteVpcEndpointServiceConfigurationsInput{
+				ServiceIds: []*string{service.ServiceId},
+			})
+			if err != nil {
+				return err
+			}
+			return unsuccessfulItemsError(result.Unsuccessful)
+		})
+		if err != nil {
+			fmt.Printf("Error deleting endpoint service: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("VPC endpoint services deleted.")
+	return nil
+}
+
+// sortedKeys returns the non-empty keys of the set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/networkfirewall"
+	"time"
+)
+
+// firewallDeleteTimeout bounds how long we wait for a firewall or firewall policy to finish deleting.
+const firewallDeleteTimeout = 20 * time.Minute
+
+// ListFirewallsForVpc lists all AWS Network Firewall firewalls in the specified VPC.
+func ListFirewallsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*networkfirewall.DescribeFirewallOutput, error) {
+	svc := networkfirewall.New(sess)
+
+	var metadata []*networkfirewall.FirewallMetadata
+	err := svc.ListFirewallsPagesWithContext(ctx, &networkfirewall.ListFirewallsInput{
+		VpcIds: []*string{aws.String(vpcID)},
+	}, func(page *networkfirewall.ListFirewallsOutput, lastPage bool) bool {
+		metadata = append(metadata, page.Firewalls...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list firewalls for VPC %s: %v", vpcID, err)
+	}
+
+	var firewalls []*networkfirewall.DescribeFirewallOutput
+	for _, fw := range metadata {
+		described, err := svc.DescribeFirewallWithContext(ctx, &networkfirewall.DescribeFirewallInput{
+			FirewallArn: fw.FirewallArn,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe firewall %s: %v", aws.StringValue(fw.FirewallName), err)
+		}
+		firewalls = append(firewalls, described)
+	}
+	return firewalls, nil
+}
+
+// FirewallEndpointIds returns the IDs of the VPC endpoints that the firewalls own.  These endpoints are
+// managed by Network Firewall and disappear when the firewall is deleted; they cannot be deleted directly.
+func FirewallEndpointIds(firewalls []*networkfirewall.DescribeFirewallOutput) map[string]bool {
+	ids := map[string]bool{}
+	for _, fw := range firewalls {
+		if fw.FirewallStatus == nil {
+			continue
+		}
+		for _, state := range fw.FirewallStatus.SyncStates {
+			if state.Attachment != nil && state.Attachment.EndpointId != nil {
+				ids[aws.StringValue(state.Attachment.EndpointId)] = true
+			}
+		}
+	}
+	return ids
+}
+
+// DeleteFirewalls deletes the specified firewalls and waits for each to be gone.  Firewalls with delete protection
+// enabled are refused; the protection has to be turned off by hand.  When deletePolicies is set,
+// the firewall policies and the rule groups they reference are deleted afterwards; policies or rule groups
+// still used by another firewall will fail to delete and are reported.  Every firewall, policy and rule group
+// is attempted; the failures are collected and returned together.
+func DeleteFirewalls(ctx context.Context, sess *session.Session, firewalls []*networkfirewall.DescribeFirewallOutput, deletePolicies bool) error {
+	fmt.Println("Deleting Network Firewall firewalls...")
+	// Create a new Network Firewall client using the provided session.
+	nfwSvc := networkfirewall.New(sess)
+
+	var errs MultiError
+	var refused []string
+	// Firewalls often share a policy, and policies a rule group, so each is only deleted once.
+	var policyArns []*string
+	seenPolicies := map[string]bool{}
+	seenRuleGroups := map[string]bool{}
+	for _, fw := range firewalls {
+		name := aws.StringValue(fw.Firewall.FirewallName)
+		if aws.BoolValue(fw.Firewall.DeleteProtection) {
+			fmt.Printf("Refusing to delete firewall %s: delete protection is enabled.\n", name)
+			refused = append(refused, name)
+			continue
+		}
+
+		fmt.Printf("Deleting firewall %s...\n", name)
+		err := forceAction("delete", "firewall", name, func() error {
+			_, err := nfwSvc.DeleteFirewallWithContext(ctx, &networkfirewall.DeleteFirewallInput{
+				FirewallArn: fw.Firewall.FirewallArn,
+			})
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Waiting for firewall %s to be deleted...\n", name)
+			return waitUntilNetworkFirewallResourceGone(ctx, func() error {
+				_, err := nfwSvc.DescribeFirewallWithContext(ctx, &networkfirewall.DescribeFirewallInput{
+					FirewallArn: fw.Firewall.FirewallArn,
+				})
+				return err
+			})
+		})
+		if err != nil {
+			fmt.Printf("Error deleting firewall: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		if forceFlag && !seenPolicies[aws.StringValue(fw.Firewall.FirewallPolicyArn)] {
+			seenPolicies[aws.StringValue(fw.Firewall.FirewallPolicyArn)] = true
+			policyArns = append(policyArns, fw.Firewall.FirewallPolicyArn)
+		}
+	}
+
+	if deletePolicies {
+		for _, policyArn := range policyArns {
+			var ruleGroupArns []*string
+			err := forceAction("delete", "firewall policy", aws.StringValue(policyArn), func() error {
+				var err error
+				ruleGroupArns, err = deleteFirewallPolicy(ctx, nfwSvc, policyArn)
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Error deleting firewall policy: %v\n", err)
+				errs = append(errs, err)
+				continue
+			}
+
+			for _, ruleGroupArn := range ruleGroupArns {
+				if seenRuleGroups[aws.StringValue(ruleGroupArn)] {
+					continue
+				}
+				seenRuleGroups[aws.StringValue(ruleGroupArn)] = true
+				fmt.Printf("Deleting firewall rule group %s...\n", aws.StringValue(ruleGroupArn))
+				err := forceAction("delete", "firewall rule group", aws.StringValue(ruleGroupArn), func() error {
+					_, err := nfwSvc.DeleteRuleGroupWithContext(ctx, &networkfirewall.DeleteRuleGroupInput{
+						RuleGroupArn: ruleGroupArn,
+					})
+					return err
+				})
+				if err != nil {
+					fmt.Printf("Error deleting firewall rule group: %v\n", err)
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	if len(refused) > 0 {
+		errs = append(errs, fmt.Errorf("refused to delete firewalls with delete protection: %v", refused))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Network Firewall firewalls deleted.")
+	return nil
+}
+
+// deleteFirewallPolicy deletes a firewall policy and waits for it to be gone.  It returns the rule groups the
+// policy referenced, which can only be deleted once the policy is.
+func deleteFirewallPolicy(ctx context.Context, nfwSvc *networkfirewall.NetworkFirewall, policyArn *string) ([]*string, error) {
+	policy, err := nfwSvc.DescribeFirewallPolicyWithContext(ctx, &networkfirewall.DescribeFirewallPolicyInput{
+		FirewallPolicyArn: policyArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ruleGroupArns []*string
+	if policy.FirewallPolicy != nil {
+		for _, ref := range policy.FirewallPolicy.StatefulRuleGroupReferences {
+			ruleGroupArns = append(ruleGroupArns, ref.ResourceArn)
+		}
+		for _, ref := range policy.FirewallPolicy.StatelessRuleGroupReferences {
+			ruleGroupArns = append(ruleGroupArns, ref.ResourceArn)
+		}
+	}
+
+	fmt.Printf("Deleting firewall policy %s...\n", aws.StringValue(policy.FirewallPolicyResponse.FirewallPolicyName))
+	_, err = nfwSvc.DeleteFirewallPolicyWithContext(ctx, &networkfirewall.DeleteFirewallPolicyInput{
+		FirewallPolicyArn: policyArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = waitUntilNetworkFirewallResourceGone(ctx, func() error {
+		_, err := nfwSvc.DescribeFirewallPolicyWithContext(ctx, &networkfirewall.DescribeFirewallPolicyInput{
+			FirewallPolicyArn: policyArn,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ruleGroupArns, nil
+}
+
+// waitUntilNetworkFirewallResourceGone polls describe until it returns ResourceNotFoundException.
+func waitUntilNetworkFirewallResourceGone(ctx context.Context, describe func() error) error {
+	deadline := time.Now().Add(firewallDeleteTimeout)
+	for {
+		err := describe()
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == networkfirewall.ErrCodeResourceNotFoundException {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for deletion")
+		}
+		if err := sleepContext(ctx, 15*time.Second); err != nil {
+			return err
+		}
+	}
+}
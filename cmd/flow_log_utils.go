@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ListFlowLogsForResources lists all flow logs attached to the specified resource IDs (VPCs, subnets or network interfaces).
+func ListFlowLogsForResources(ctx context.Context, sess *session.Session, resourceIDs []string) ([]*ec2.FlowLog, error) {
+	if len(resourceIDs) == 0 {
+		return nil, nil
+	}
+	svc := ec2.New(sess)
+
+	// A filter takes at most 200 values.
+	var flowLogs []*ec2.FlowLog
+	for start := 0; start < len(resourceIDs); start += 200 {
+		end := start + 200
+		if end > len(resourceIDs) {
+			end = len(resourceIDs)
+		}
+		input := &ec2.DescribeFlowLogsInput{
+			Filter: []*ec2.Filter{
+				{
+					Name:   aws.String("resource-id"),
+					Values: aws.StringSlice(resourceIDs[start:end]),
+				},
+			},
+		}
+
+		err := svc.DescribeFlowLogsPagesWithContext(ctx, input, func(page *ec2.DescribeFlowLogsOutput, lastPage bool) bool {
+			flowLogs = append(flowLogs, page.FlowLogs...)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list flow logs: %v", err)
+		}
+	}
+
+	return flowLogs, nil
+}
+
+// ListFlowLogsForVpc lists the flow logs attached to the VPC itself, its subnets and its network interfaces.
+func ListFlowLogsForVpc(ctx context.Context, sess *session.Session, vpcID string, subnets []*ec2.Subnet) ([]*ec2.FlowLog, error) {
+	resourceIDs := []string{vpcID}
+	for _, subnet := range subnets {
+		resourceIDs = append(resourceIDs, aws.StringValue(subnet.SubnetId))
+	}
+
+	enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range enis {
+		resourceIDs = append(resourceIDs, aws.StringValue(eni.NetworkInterfaceId))
+	}
+
+	return ListFlowLogsForResources(ctx, sess, resourceIDs)
+}
+
+// DeleteFlowLogs deletes the specified flow logs.  The CloudWatch log groups or S3 buckets they write to are left
+// alone.  Every flow log is attempted; the failures are collected and returned together.
+func DeleteFlowLogs(ctx context.Context, sess *session.Session, flowLogs []*ec2.FlowLog) error {
+	fmt.Println("Deleting flow logs...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, flowLog := range flowLogs {
+		fmt.Printf("Deleting flow log %s for %s (destination %s)...\n",
+			aws.StringValue(flowLog.FlowLogId), aws.StringValue(flowLog.ResourceId), flowLogDestination(flowLog))
+		err := forceAction("delete", "flow log", aws.StringValue(flowLog.FlowLogId), func() error {
+			result, err := ec2Svc.DeleteFlowLogsWithContext(ctx, &ec2.DeleteFlowLogsInput{
+				FlowLogIds: []*string{flowLog.FlowLogId},
+			})
+			if err != nil {
+				return err
+			}
+			return unsuccessfulItemsError(result.Unsuccessful)
+		})
+		if err != nil {
+			fmt.Printf("Error deleting flow log: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Flow logs deleted.")
+	return nil
+}
+
+// flowLogDestination returns a printable description of where a flow log delivers its records.
+func flowLogDestination(flowLog *ec2.FlowLog) string {
+	if flowLog.LogGroupName != nil {
+		return aws.StringValue(flowLog.LogGroupName)
+	}
+	return aws.StringValue(flowLog.LogDestination)
+}
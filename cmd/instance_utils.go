@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ListInstancesForVpc lists all instances in the specified VPC that have not already been terminated.
+func ListInstancesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.Instance, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{"pending", "running", "shutting-down", "stopping", "stopped"}),
+			},
+		},
+	}
+
+	result, err := svc.DescribeInstancesWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances for VPC %s: %v", vpcID, err)
+	}
+
+	var instances []*ec2.Instance
+	for _, reservation := range result.Reservations {
+		instances = append(instances, reservation.Instances...)
+	}
+	return instances, nil
+}
+
+// TerminateInstances terminates the specified instances and waits for them to reach the terminated state.
+// Instances carrying the protection tag are refused.  Termination protection is only turned off when --force is given.
+func TerminateInstances(ctx context.Context, sess *session.Session, instances []*ec2.Instance) error {
+	fmt.Println("Terminating instances...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	var refused []string
+	var terminated []*string
+	for _, instance := range instances {
+		instanceID := aws.StringValue(instance.InstanceId)
+		name := getNameTag(instance.Tags)
+
+		if hasTag(instance.Tags, protectionTag) {
+			fmt.Printf("Refusing to terminate instance %s (%s): it carries the %s tag.\n", instanceID, name, protectionTag)
+			refused = append(refused, instanceID)
+			continue
+		}
+
+		fmt.Printf("Terminating instance %s (%s)...\n", instanceID, name)
+		err := forceAction("terminate", "instance", instanceID, func() error {
+			attr, err := ec2Svc.DescribeInstanceAttributeWithContext(ctx, &ec2.DescribeInstanceAttributeInput{
+				InstanceId: instance.InstanceId,
+				Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+			})
+			if err != nil {
+				return err
+			}
+			if attr.DisableApiTermination != nil && aws.BoolValue(attr.DisableApiTermination.Value) {
+				fmt.Printf("Disabling termination protection on instance %s...\n", instanceID)
+				_, err := ec2Svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+					InstanceId:            instance.InstanceId,
+					DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(false)},
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = ec2Svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+				InstanceIds: []*string{instance.InstanceId},
+			})
+			if err != nil {
+				return err
+			}
+			terminated = append(terminated, instance.InstanceId)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error terminating instance: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(terminated) > 0 {
+		fmt.Printf("Waiting for %d instances to be terminated...\n", len(terminated))
+		err := ec2Svc.WaitUntilInstanceTerminatedWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: terminated,
+		})
+		if err != nil {
+			results.Record("wait for", "instances", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(refused) > 0 {
+		errs = append(errs, fmt.Errorf("refused to terminate protected instances: %v", refused))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Instances terminated.")
+	return nil
+}
+
+// hasTag reports whether the tag set contains the specified key.
+func hasTag(tags []*ec2.Tag, key string) bool {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
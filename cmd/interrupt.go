@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// ExitInterrupted is the exit code of a run that was stopped with Ctrl-C.
+const ExitInterrupted = 130
+
+// errInterrupted is returned for every AWS API call that is not started because the run was interrupted.
+var errInterrupted = errors.New("interrupted before the operation started")
+
+// interrupted is closed on the first SIGINT.
+var interrupted = make(chan struct{})
+
+// Interrupted reports whether the run has been interrupted.
+func Interrupted() bool {
+	select {
+	case <-interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// isInterruptedError reports whether err comes from an operation that was never started because the run was
+// interrupted.  Errors are wrapped with %v throughout, so this has to go by the message.
+func isInterruptedError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), errInterrupted.Error())
+}
+
+// HandleInterrupts returns a context for the run.  On the first SIGINT the run stops starting new AWS API calls,
+// lets the ones in flight finish, and unwinds so that the summary and journal get written.  On the second SIGINT
+// the context is canceled and the process exits immediately.
+func HandleInterrupts() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "\nInterrupted: finishing in-flight operations. Press Ctrl-C again to exit immediately.")
+		close(interrupted)
+
+		<-signals
+		fmt.Fprintln(os.Stderr, "\nExiting immediately.")
+		cancel()
+		os.Exit(ExitInterrupted)
+	}()
+
+	return ctx
+}
+
+// refuseWhenInterrupted is a request handler that fails every request built after the run was interrupted.
+// Requests already sent, including their retries, are not affected.
+func refuseWhenInterrupted(r *request.Request) {
+	if Interrupted() {
+		r.Error = errInterrupted
+	}
+}
+
+// sleepContext sleeps for d between polls.  It returns early, with an error, when the run is interrupted or ctx is
+// canceled, so that waits do not hold up a graceful stop.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-interrupted:
+		return errInterrupted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
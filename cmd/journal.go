@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// OperationComplete marks, in the journal, a scope (a region or a VPC) in which every operation succeeded.
+// A resumed run skips completed scopes without listing their resources again.
+const OperationComplete = "complete"
+
+// JournalEntry is one line of the run journal.
+type JournalEntry struct {
+	Time time.Time `json:"time"`
+	Result
+}
+
+// Journal appends every recorded result to a JSON Lines file, and remembers what a previous run already
+// finished so that a resumed run can skip it.
+type Journal struct {
+	path string
+	file *os.File
+	done map[string]bool
+}
+
+// journal is the journal for the current run.
+var journal = &Journal{}
+
+var (
+	journalPath string
+	resumePath  string
+)
+
+// journalKey identifies an operation on a resource in a scope.
+func journalKey(account, region, vpcID, operation, resource string) string {
+	return strings.Join([]string{account, region, vpcID, operation, resource}, "\t")
+}
+
+// OpenJournal prepares the journal for the run.  When resume is set, the entries of that journal are loaded,
+// and new entries are appended to path, or to the resumed journal when path is empty.  Without either, no journal
+// is written.  The file itself is only created once the first entry is written.
+func OpenJournal(path, resume string) error {
+	journal.done = map[string]bool{}
+	journal.path = path
+	if resume != "" {
+		err := journal.load(resume)
+		if err != nil {
+			return err
+		}
+		if journal.path == "" {
+			journal.path = resume
+		}
+	}
+	return nil
+}
+
+// load reads a journal written by a previous run.  The last entry for an operation wins, so an operation
+// that failed and then succeeded in a later run counts as done.
+func (j *Journal) load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open journal %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry JournalEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return fmt.Errorf("failed to parse journal %s, line %d: %v", path, line, err)
+		}
+		key := journalKey(entry.Account, entry.Region, entry.VpcID, entry.Operation, entry.Resource)
+		j.done[key] = entry.Outcome == OutcomeSuccess
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read journal %s: %v", path, err)
+	}
+
+	fmt.Printf("Resuming from journal %s (%d entries).\n", path, line)
+	return nil
+}
+
+// Done reports whether a previous run already completed the operation on the resource in the scope.
+func (j *Journal) Done(account, region, vpcID, operation, resource string) bool {
+	return j.done[journalKey(account, region, vpcID, operation, resource)]
+}
+
+// Write appends a result to the journal.  The journal is only a record of the run, so failing to write it
+// is reported but does not stop the run.
+func (j *Journal) Write(result Result) {
+	if j.path == "" {
+		return
+	}
+	if j.file == nil {
+		file, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Printf("failed to open journal %s: %v\n", j.path, err)
+			j.path = ""
+			return
+		}
+		j.file = file
+	}
+
+	line, err := json.Marshal(JournalEntry{Time: time.Now().UTC(), Result: result})
+	if err == nil {
+		_, err = j.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		fmt.Printf("failed to write journal %s: %v\n", j.path, err)
+	}
+}
+
+// Close closes the journal file, if one was written, and says where it is.
+func (j *Journal) Close() {
+	if j.file == nil {
+		return
+	}
+	j.file.Close()
+	fmt.Printf("Journal written to %s. Use --resume %s to pick up where this run left off.\n", j.path, j.path)
+}
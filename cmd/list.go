@@ -1,9 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"time"
 )
 
 var listCmd = &cobra.Command{
@@ -13,32 +14,80 @@ var listCmd = &cobra.Command{
 	RunE:  listFunc,
 }
 
+var (
+	showDependencies bool
+)
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolVar(&showDependencies, "show-dependencies", false, "Show the managed-service resources (RDS, ElastiCache, EFS, Lambda) and RAM shares that block subnet deletion")
+	listCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only select VPCs created at least this long ago, such as 72h")
+	listCmd.Flags().StringVar(&createdBefore, "created-before", "", "Only select VPCs created before this date (2006-01-02) or RFC 3339 time")
+	listCmd.Flags().StringVar(&creationTag, "creation-tag", "CreatedAt", "Tag key holding a VPC's creation time, used before the times of its NAT gateways and endpoints")
 }
 
 func listFunc(cmd *cobra.Command, args []string) error {
-	regionList := viper.GetStringSlice("region-list")
-	profileList := viper.GetStringSlice("profile-list")
+	ctx := cmd.Context()
+
+	if err := SetAgeCutoff(time.Now()); err != nil {
+		return err
+	}
 
 	// Print the list of security groups in each region and profile.
-	err := IterateOverProfiles(profileList, func(profile string) error {
-		return IterateOverRegions(regionList, func(region string) error {
+	err := IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
 			// Use the current profile and region to create a new session.
 			sess, err := GetSession(profile, region)
 			if err != nil {
 				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
 			}
 
+			if len(accountAllowlist) > 0 {
+				account := AccountLabel(ctx, sess, profile)
+				if !AccountAllowed(account) {
+					fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, account)
+					return nil
+				}
+			}
+
 			// List the VPC resources using the session.
-			vpcs, err2 := ListVpcs(sess)
+			vpcs, err2 := ListVpcs(ctx, sess)
 			if err2 != nil {
 				return fmt.Errorf("failed to list VPC resources: %v", err2)
 			}
+			vpcs, err = SelectVpcsByAge(ctx, sess, SelectVpcs(vpcs))
+			if err != nil {
+				return fmt.Errorf("failed to determine VPC creation times: %v", err)
+			}
 			// Print the list of VPCs in the current region and profile.
 			fmt.Printf("VPCs in %s (%s):\n", profile, region)
 			for _, vpc := range vpcs {
 				fmt.Printf("\t%s\n", vpc)
+
+				if showDependencies {
+					deps, err := ListSubnetDependenciesForVpc(ctx, sess, *vpc.VpcId)
+					if err != nil {
+						return fmt.Errorf("failed to scan subnet dependencies: %v", err)
+					}
+					PrintSubnetDependencies(deps)
+
+					subnets, err := ListSubnetsForVpc(ctx, sess, *vpc.VpcId)
+					if err != nil {
+						return err
+					}
+					shares, err := ListSubnetSharesForVpc(ctx, sess, subnets)
+					if err != nil {
+						return fmt.Errorf("failed to list RAM shares: %v", err)
+					}
+					if len(shares) > 0 {
+						participantEnis, err := ListParticipantEnisForVpc(ctx, sess, *vpc.VpcId)
+						if err != nil {
+							return err
+						}
+						PrintSubnetShares(shares, participantEnis)
+					}
+				}
 			}
 
 			return nil
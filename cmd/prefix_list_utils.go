@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+// ListPrefixListsOnlyUsedBy finds the customer-managed prefix lists referenced by those of the VPC's security
+// groups and route tables that are about to be deleted, and returns those owned by this account that nothing else
+// references.  The default security group only counts when it is reset, and the main route table never does,
+// since both outlive the prefix lists.  Prefix lists shared from other accounts through RAM are reported and
+// never returned.
+func ListPrefixListsOnlyUsedBy(ctx context.Context, sess *session.Session, sgs []*ec2.SecurityGroup, defaultSgID string, routeTables []*ec2.RouteTable) ([]*ec2.ManagedPrefixList, error) {
+	svc := ec2.New(sess)
+
+	deleting := map[string]bool{}
+	referenced := map[string]bool{}
+	for _, sg := range sgs {
+		if !typeSelected(ResourceSecurityGroup) || (isDefaultSg(sg, defaultSgID) && !resettingDefaults()) {
+			continue
+		}
+		deleting[aws.StringValue(sg.GroupId)] = true
+		for _, perm := range append(sg.IpPermissions, sg.IpPermissionsEgress...) {
+			for _, pl := range perm.PrefixListIds {
+				referenced[aws.StringValue(pl.PrefixListId)] = true
+			}
+		}
+	}
+	for _, table := range routeTables {
+		if !typeSelected(ResourceRouteTable) || isMainRouteTable(table) {
+			continue
+		}
+		deleting[aws.StringValue(table.RouteTableId)] = true
+		for _, route := range table.Routes {
+			if route.DestinationPrefixListId != nil {
+				referenced[aws.StringValue(route.DestinationPrefixListId)] = true
+			}
+		}
+	}
+	if len(referenced) == 0 {
+		return nil, nil
+	}
+
+	accountID, err := GetAccountID(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := svc.DescribeManagedPrefixListsWithContext(ctx, &ec2.DescribeManagedPrefixListsInput{
+		PrefixListIds: aws.StringSlice(sortedKeys(referenced)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe prefix lists: %v", err)
+	}
+
+	var candidates []*ec2.ManagedPrefixList
+	for _, pl := range result.PrefixLists {
+		plID := aws.StringValue(pl.PrefixListId)
+		owner := aws.StringValue(pl.OwnerId)
+		if owner == "AWS" {
+			continue
+		}
+		if owner != accountID {
+			fmt.Printf("Prefix list %s (%s) is shared from account %s, leaving it alone...\n", plID, aws.StringValue(pl.PrefixListName), owner)
+			continue
+		}
+
+		associations, err := svc.GetManagedPrefixListAssociationsWithContext(ctx, &ec2.GetManagedPrefixListAssociationsInput{
+			PrefixListId: pl.PrefixListId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list associations of prefix list %s: %v", plID, err)
+		}
+		var others []string
+		for _, assoc := range associations.PrefixListAssociations {
+			if !deleting[aws.StringValue(assoc.ResourceId)] {
+				others = append(others, aws.StringValue(assoc.ResourceId))
+			}
+		}
+		if len(others) > 0 {
+			fmt.Printf("Prefix list %s (%s) is still used by %s, leaving it alone...\n", plID, aws.StringValue(pl.PrefixListName), strings.Join(others, ", "))
+			continue
+		}
+		candidates = append(candidates, pl)
+	}
+
+	return candidates, nil
+}
+
+// DeleteManagedPrefixLists deletes the specified prefix lists.  It must run after the security groups and
+// route tables that referenced them have been deleted.
+func DeleteManagedPrefixLists(ctx context.Context, sess *session.Session, prefixLists []*ec2.ManagedPrefixList) error {
+	fmt.Println("Deleting managed prefix lists...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, pl := range prefixLists {
+		fmt.Printf("Deleting managed prefix list %s (%s)...\n", aws.StringValue(pl.PrefixListId), aws.StringValue(pl.PrefixListName))
+		err := forceAction("delete", "managed prefix list", aws.StringValue(pl.PrefixListId), func() error {
+			_, err := ec2Svc.DeleteManagedPrefixListWithContext(ctx, &ec2.DeleteManagedPrefixListInput{
+				PrefixListId: pl.PrefixListId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting managed prefix list: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Managed prefix lists deleted.")
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check that every delete operation would be permitted, without deleting anything",
+	Long: "Walk the plan that delete would execute and issue each EC2 Delete/Detach/Release call with DryRun set. " +
+		"Reports, per account and region, which operations would be denied, and exits non-zero if any would be " +
+		"or if a resource could not be listed or checked.",
+	RunE: preflightFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(preflightCmd)
+
+	// The plan depends on the same opt-in stages as delete.
+	preflightCmd.Flags().BoolVar(&includeInstances, "include-instances", false, "Also check termination of EC2 instances in the VPC")
+	preflightCmd.Flags().BoolVar(&keepVpc, "keep-vpc", false, "Do not check deletion of the VPC itself")
+	preflightCmd.Flags().BoolVar(&resetDefaults, "reset-defaults", false, "With --keep-vpc, also check resetting the default network ACL and security group")
+	preflightCmd.Flags().StringSliceVar(&resourceTypes, "resource-types", nil, "Comma-separated list of resource types to check (default all): "+strings.Join(allResourceTypes, ", "))
+	preflightCmd.Flags().StringSliceVar(&excludeResourceTypes, "exclude-resource-types", nil, "Comma-separated list of resource types not to check")
+	preflightCmd.Flags().DurationVar(&olderThan, "older-than", 0, "Only select VPCs created at least this long ago, such as 72h")
+	preflightCmd.Flags().StringVar(&createdBefore, "created-before", "", "Only select VPCs created before this date (2006-01-02) or RFC 3339 time")
+	preflightCmd.Flags().StringVar(&creationTag, "creation-tag", "CreatedAt", "Tag key holding a VPC's creation time, used before the times of its NAT gateways and endpoints")
+}
+
+func preflightFunc(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := SetAgeCutoff(time.Now())
+	if err != nil {
+		return err
+	}
+
+	selectedResourceTypes, err = SelectResourceTypes(resourceTypes, excludeResourceTypes)
+	if err != nil {
+		return err
+	}
+	WarnResourceTypeDependencies(selectedResourceTypes)
+
+	totalDenied := 0
+	totalErrors := 0
+	err = IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
+			// Use the current profile and region to create a new session.
+			sess, err := GetSession(profile, region)
+			if err != nil {
+				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
+			}
+
+			accountID, err := GetAccountID(ctx, sess)
+			if err != nil {
+				return err
+			}
+			if !AccountAllowed(accountID) {
+				fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, accountID)
+				return nil
+			}
+
+			vpcs, err := ListVpcs(ctx, sess)
+			if err != nil {
+				return fmt.Errorf("failed to list VPC resources: %v", err)
+			}
+			vpcs, err = SelectVpcsByAge(ctx, sess, SelectVpcs(vpcs))
+			if err != nil {
+				return fmt.Errorf("failed to determine VPC creation times: %v", err)
+			}
+
+			fmt.Printf("Preflight for account %s, profile %s (%s):\n", accountID, profile, region)
+			for _, vpc := range vpcs {
+				fmt.Printf("VPC %s:\n", *vpc.VpcId)
+				denied, failed := PrintPreflightResults(PreflightVpc(ctx, sess, vpc))
+				totalDenied += denied
+				totalErrors += failed
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to IterateOverProfiles: %v", err)
+	}
+
+	if totalDenied > 0 || totalErrors > 0 {
+		return fmt.Errorf("preflight failed: %d operations would be denied, %d checks could not be made", totalDenied, totalErrors)
+	}
+	fmt.Println("Preflight passed.")
+	return nil
+}
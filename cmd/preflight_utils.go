@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Outcomes of a preflight DryRun call.
+const (
+	preflightAllowed = "allowed"
+	preflightDenied  = "denied"
+	preflightError   = "error"
+)
+
+// PreflightResult is the outcome of one DryRun call against one resource.
+type PreflightResult struct {
+	Operation string
+	Resource  string
+	Outcome   string
+	Message   string
+}
+
+// classifyDryRun maps the error of a DryRun call to a preflight outcome.  EC2 answers a permitted DryRun
+// request with DryRunOperation and a forbidden one with UnauthorizedOperation.
+func classifyDryRun(err error) (string, string) {
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case "DryRunOperation":
+			return preflightAllowed, ""
+		case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException":
+			return preflightDenied, aerr.Message()
+		}
+		return preflightError, aerr.Code() + ": " + aerr.Message()
+	}
+	if err == nil {
+		return preflightAllowed, ""
+	}
+	return preflightError, err.Error()
+}
+
+// PreflightVpc walks the EC2 part of the plan that DeleteVpc would execute for the VPC, limited to the selected
+// resource types, and issues every Delete*, Detach*, Release*, Revoke*, Disassociate*, Replace* and Modify* call,
+// along with the entry and rule calls of --reset-defaults, with DryRun set, so nothing is modified.
+// DisassociateVpcCidrBlock and the APIs of other services (ELB, Network Firewall, Route 53, RAM, ...) have no DryRun mode and are not checked.
+func PreflightVpc(ctx context.Context, sess *session.Session, vpc *ec2.Vpc) []PreflightResult {
+	svc := ec2.New(sess)
+	dryRun := aws.Bool(true)
+	vpcID := aws.StringValue(vpc.VpcId)
+
+	var checks []PreflightResult
+	check := func(operation, resource string, call func() error) {
+		outcome, message := classifyDryRun(call())
+		checks = append(checks, PreflightResult{Operation: operation, Resource: resource, Outcome: outcome, Message: message})
+	}
+	listFailed := func(operation string, err error) {
+		checks = append(checks, PreflightResult{Operation: operation, Resource: vpcID, Outcome: preflightError, Message: err.Error()})
+	}
+
+	subnets, err := ListSubnetsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		listFailed("DescribeSubnets", err)
+	}
+
+	if typeSelected(ResourceVpc) {
+		flowLogs, err := ListFlowLogsForVpc(ctx, sess, vpcID, subnets)
+		if err != nil {
+			listFailed("DescribeFlowLogs", err)
+		}
+		for _, flowLog := range flowLogs {
+			check("DeleteFlowLogs", aws.StringValue(flowLog.FlowLogId), func() error {
+				_, err := svc.DeleteFlowLogsWithContext(ctx, &ec2.DeleteFlowLogsInput{DryRun: dryRun, FlowLogIds: []*string{flowLog.FlowLogId}})
+				return err
+			})
+		}
+	}
+
+	if includeInstances && typeSelected(ResourceSubnet) {
+		instances, err := ListInstancesForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeInstances", err)
+		}
+		for _, instance := range instances {
+			if hasTag(instance.Tags, protectionTag) {
+				continue
+			}
+			attr, err := svc.DescribeInstanceAttributeWithContext(ctx, &ec2.DescribeInstanceAttributeInput{
+				InstanceId: instance.InstanceId,
+				Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+			})
+			if err != nil {
+				listFailed("DescribeInstanceAttribute", err)
+			} else if attr.DisableApiTermination != nil && aws.BoolValue(attr.DisableApiTermination.Value) {
+				check("ModifyInstanceAttribute", aws.StringValue(instance.InstanceId), func() error {
+					_, err := svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+						DryRun: dryRun, InstanceId: instance.InstanceId, DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(false)}})
+					return err
+				})
+			}
+			check("TerminateInstances", aws.StringValue(instance.InstanceId), func() error {
+				_, err := svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{DryRun: dryRun, InstanceIds: []*string{instance.InstanceId}})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceSubnet) {
+		mirrorSessions, mirrorTargets, err := ListTrafficMirrorResourcesForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeTrafficMirrorSessions", err)
+		}
+		for _, mirrorSession := range mirrorSessions {
+			check("DeleteTrafficMirrorSession", aws.StringValue(mirrorSession.TrafficMirrorSessionId), func() error {
+				_, err := svc.DeleteTrafficMirrorSessionWithContext(ctx, &ec2.DeleteTrafficMirrorSessionInput{DryRun: dryRun, TrafficMirrorSessionId: mirrorSession.TrafficMirrorSessionId})
+				return err
+			})
+		}
+		for _, target := range mirrorTargets {
+			check("DeleteTrafficMirrorTarget", aws.StringValue(target.TrafficMirrorTargetId), func() error {
+				_, err := svc.DeleteTrafficMirrorTargetWithContext(ctx, &ec2.DeleteTrafficMirrorTargetInput{DryRun: dryRun, TrafficMirrorTargetId: target.TrafficMirrorTargetId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceSubnet) {
+		endpointServices, err := ListEndpointServicesForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeVpcEndpointServiceConfigurations", err)
+		}
+		for _, service := range endpointServices {
+			check("DeleteVpcEndpointServiceConfigurations", aws.StringValue(service.ServiceId), func() error {
+				_, err := svc.DeleteVpcEndpointServiceConfigurationsWithContext(ctx, &ec2.DeleteVpcEndpointServiceConfigurationsInput{DryRun: dryRun, ServiceIds: []*string{service.ServiceId}})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceSubnet) {
+		clientVpnEndpoints, err := ListClientVpnEndpointsForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeClientVpnEndpoints", err)
+		}
+		for _, endpoint := range clientVpnEndpoints {
+			for _, target := range endpoint.Targets {
+				check("DisassociateClientVpnTargetNetwork", aws.StringValue(target.AssociationId), func() error {
+					_, err := svc.DisassociateClientVpnTargetNetworkWithContext(ctx, &ec2.DisassociateClientVpnTargetNetworkInput{
+						DryRun: dryRun, ClientVpnEndpointId: endpoint.Endpoint.ClientVpnEndpointId, AssociationId: target.AssociationId})
+					return err
+				})
+			}
+			check("DeleteClientVpnEndpoint", aws.StringValue(endpoint.Endpoint.ClientVpnEndpointId), func() error {
+				_, err := svc.DeleteClientVpnEndpointWithContext(ctx, &ec2.DeleteClientVpnEndpointInput{DryRun: dryRun, ClientVpnEndpointId: endpoint.Endpoint.ClientVpnEndpointId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceVpcEndpoint) {
+		vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeVpcEndpoints", err)
+		}
+		for _, vpcEndpoint := range vpcEndpoints {
+			if aws.BoolValue(vpcEndpoint.RequesterManaged) {
+				continue
+			}
+			check("DeleteVpcEndpoints", aws.StringValue(vpcEndpoint.VpcEndpointId), func() error {
+				_, err := svc.DeleteVpcEndpointsWithContext(ctx, &ec2.DeleteVpcEndpointsInput{DryRun: dryRun, VpcEndpointIds: []*string{vpcEndpoint.VpcEndpointId}})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceNatGateway) {
+		natGateways, err := ListNatGatewaysForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeNatGateways", err)
+		}
+		for _, natGw := range natGateways {
+			check("DeleteNatGateway", aws.StringValue(natGw.NatGatewayId), func() error {
+				_, err := svc.DeleteNatGatewayWithContext(ctx, &ec2.DeleteNatGatewayInput{DryRun: dryRun, NatGatewayId: natGw.NatGatewayId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceEip) {
+		eips, err := ListEipsForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeAddresses", err)
+		}
+		for _, eip := range eips {
+			check("ReleaseAddress", aws.StringValue(eip.PublicIp), func() error {
+				_, err := svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{DryRun: dryRun, AllocationId: eip.AllocationId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceVpc) {
+		carrierGateways, err := ListCarrierGatewaysForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeCarrierGateways", err)
+		}
+		for _, cgw := range carrierGateways {
+			check("DeleteCarrierGateway", aws.StringValue(cgw.CarrierGatewayId), func() error {
+				_, err := svc.DeleteCarrierGatewayWithContext(ctx, &ec2.DeleteCarrierGatewayInput{DryRun: dryRun, CarrierGatewayId: cgw.CarrierGatewayId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceVpc) {
+		lgwAssociations, err := ListLocalGatewayRouteTableVpcAssociationsForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeLocalGatewayRouteTableVpcAssociations", err)
+		}
+		for _, assoc := range lgwAssociations {
+			check("DeleteLocalGatewayRouteTableVpcAssociation", aws.StringValue(assoc.LocalGatewayRouteTableVpcAssociationId), func() error {
+				_, err := svc.DeleteLocalGatewayRouteTableVpcAssociationWithContext(ctx, &ec2.DeleteLocalGatewayRouteTableVpcAssociationInput{
+					DryRun: dryRun, LocalGatewayRouteTableVpcAssociationId: assoc.LocalGatewayRouteTableVpcAssociationId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceIgw) {
+		igws, err := ListIgwsForVpc(ctx, sess, vpcID)
+		if err != nil {
+			listFailed("DescribeInternetGateways", err)
+		}
+		for _, igw := range igws {
+			check("DetachInternetGateway", aws.StringValue(igw.InternetGatewayId), func() error {
+				_, err := svc.DetachInternetGatewayWithContext(ctx, &ec2.DetachInternetGatewayInput{DryRun: dryRun, InternetGatewayId: igw.InternetGatewayId, VpcId: vpc.VpcId})
+				return err
+			})
+			check("DeleteInternetGateway", aws.StringValue(igw.InternetGatewayId), func() error {
+				_, err := svc.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{DryRun: dryRun, InternetGatewayId: igw.InternetGatewayId})
+				return err
+			})
+		}
+	}
+
+	routeTables, err := ListRouteTablesForVpc(ctx, sess, vpc)
+	if err != nil {
+		listFailed("DescribeRouteTables", err)
+	}
+	if typeSelected(ResourceRouteTable) {
+		for _, table := range routeTables {
+			for _, association := range table.Associations {
+				if aws.BoolValue(association.Main) {
+					continue
+				}
+				check("DisassociateRouteTable", aws.StringValue(association.RouteTableAssociationId), func() error {
+					_, err := svc.DisassociateRouteTableWithContext(ctx, &ec2.DisassociateRouteTableInput{DryRun: dryRun, AssociationId: association.RouteTableAssociationId})
+					return err
+				})
+			}
+			if isMainRouteTable(table) {
+				for _, route := range table.Routes {
+					if !isDeletableRoute(route) {
+						continue
+					}
+					check("DeleteRoute", aws.StringValue(table.RouteTableId)+" "+routeDestination(route), func() error {
+						_, err := svc.DeleteRouteWithContext(ctx, &ec2.DeleteRouteInput{
+							DryRun:                   dryRun,
+							RouteTableId:             table.RouteTableId,
+							DestinationCidrBlock:     route.DestinationCidrBlock,
+							DestinationIpv6CidrBlock: route.DestinationIpv6CidrBlock,
+							DestinationPrefixListId:  route.DestinationPrefixListId,
+						})
+						return err
+					})
+				}
+				continue
+			}
+			check("DeleteRouteTable", aws.StringValue(table.RouteTableId), func() error {
+				_, err := svc.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{DryRun: dryRun, RouteTableId: table.RouteTableId})
+				return err
+			})
+		}
+	}
+
+	sgs, err := ListSgsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		listFailed("DescribeSecurityGroups", err)
+	}
+	defaultSgID, err := DefaultSgIDForVpc(ctx, sess, vpcID)
+	if err != nil {
+		listFailed("DescribeSecurityGroups", err)
+	}
+	groupIDs := map[string]bool{}
+	for _, sg := range sgs {
+		groupIDs[aws.StringValue(sg.GroupId)] = true
+	}
+	if typeSelected(ResourceSecurityGroup) {
+		for _, sg := range sgs {
+			if ingress := referencingPermissions(sg.IpPermissions, groupIDs); len(ingress) > 0 {
+				check("RevokeSecurityGroupIngress", aws.StringValue(sg.GroupId), func() error {
+					_, err := svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{DryRun: dryRun, GroupId: sg.GroupId, IpPermissions: ingress})
+					return err
+				})
+			}
+			if egress := referencingPermissions(sg.IpPermissionsEgress, groupIDs); len(egress) > 0 {
+				check("RevokeSecurityGroupEgress", aws.StringValue(sg.GroupId), func() error {
+					_, err := svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{DryRun: dryRun, GroupId: sg.GroupId, IpPermissions: egress})
+					return err
+				})
+			}
+			if isDefaultSg(sg, defaultSgID) {
+				continue
+			}
+			check("DeleteSecurityGroup", aws.StringValue(sg.GroupId), func() error {
+				_, err := svc.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{DryRun: dryRun, GroupId: sg.GroupId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceSecurityGroup) {
+		prefixLists, err := ListPrefixListsOnlyUsedBy(ctx, sess, sgs, defaultSgID, routeTables)
+		if err != nil {
+			listFailed("DescribeManagedPrefixLists", err)
+		}
+		for _, pl := range prefixLists {
+			check("DeleteManagedPrefixList", aws.StringValue(pl.PrefixListId), func() error {
+				_, err := svc.DeleteManagedPrefixListWithContext(ctx, &ec2.DeleteManagedPrefixListInput{DryRun: dryRun, PrefixListId: pl.PrefixListId})
+				return err
+			})
+		}
+	}
+
+	nacls, err := ListNaclsForVpc(ctx, sess, vpc)
+	if err != nil {
+		listFailed("DescribeNetworkAcls", err)
+	}
+	var defaultNacl *ec2.NetworkAcl
+	for _, nacl := range nacls {
+		if aws.BoolValue(nacl.IsDefault) {
+			defaultNacl = nacl
+		}
+	}
+	if typeSelected(ResourceNacl) {
+		for _, nacl := range nacls {
+			if aws.BoolValue(nacl.IsDefault) {
+				continue
+			}
+			if defaultNacl != nil {
+				for _, association := range nacl.Associations {
+					check("ReplaceNetworkAclAssociation", aws.StringValue(association.NetworkAclAssociationId), func() error {
+						_, err := svc.ReplaceNetworkAclAssociationWithContext(ctx, &ec2.ReplaceNetworkAclAssociationInput{
+							DryRun: dryRun, AssociationId: association.NetworkAclAssociationId, NetworkAclId: defaultNacl.NetworkAclId})
+						return err
+					})
+				}
+			}
+			check("DeleteNetworkAcl", aws.StringValue(nacl.NetworkAclId), func() error {
+				_, err := svc.DeleteNetworkAclWithContext(ctx, &ec2.DeleteNetworkAclInput{DryRun: dryRun, NetworkAclId: nacl.NetworkAclId})
+				return err
+			})
+		}
+	}
+
+	if typeSelected(ResourceSubnet) {
+		for _, subnet := range subnets {
+			check("DeleteSubnet", aws.StringValue(subnet.SubnetId), func() error {
+				_, err := svc.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{DryRun: dryRun, SubnetId: subnet.SubnetId})
+				return err
+			})
+		}
+	}
+
+	if resettingDefaults() {
+		if defaultNacl != nil {
+			preflightResetDefaultNacl(ctx, svc, defaultNacl, check)
+		}
+		for _, sg := range sgs {
+			if isDefaultSg(sg, defaultSgID) {
+				preflightResetDefaultSg(ctx, svc, sg, check)
+			}
+		}
+	}
+
+	if keepVpc || !typeSelected(ResourceVpc) {
+		return checks
+	}
+
+	check("DeleteVpc", vpcID, func() error {
+		_, err := svc.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{DryRun: dryRun, VpcId: vpc.VpcId})
+		return err
+	})
+
+	// The DHCP options set goes once the VPC is, unless DeleteDhcpOptionsIfUnused would keep it.
+	if dhcpOptionsID := aws.StringValue(vpc.DhcpOptionsId); dhcpOptionsID != "" && dhcpOptionsID != "default" {
+		deletable, err := preflightDhcpOptionsDeletable(ctx, sess, dhcpOptionsID, vpcID)
+		if err != nil {
+			listFailed("DescribeDhcpOptions", err)
+		} else if deletable {
+			check("DeleteDhcpOptions", dhcpOptionsID, func() error {
+				_, err := svc.DeleteDhcpOptionsWithContext(ctx, &ec2.DeleteDhcpOptionsInput{DryRun: dryRun, DhcpOptionsId: vpc.DhcpOptionsId})
+				return err
+			})
+		}
+	}
+
+	return checks
+}
+
+// preflightDhcpOptionsDeletable reports whether DeleteDhcpOptionsIfUnused would delete the DHCP options set once
+// the VPC is gone: it is not the AWS-provided set, and no other VPC is associated with it.
+func preflightDhcpOptionsDeletable(ctx context.Context, sess *session.Session, dhcpOptionsID, vpcID string) (bool, error) {
+	svc := ec2.New(sess)
+
+	result, err := svc.DescribeDhcpOptionsWithContext(ctx, &ec2.DescribeDhcpOptionsInput{
+		DhcpOptionsIds: []*string{aws.String(dhcpOptionsID)},
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(result.DhcpOptions) == 0 || isAwsProvidedDhcpOptions(result.DhcpOptions[0]) {
+		return false, nil
+	}
+
+	vpcs, err := ListVpcsUsingDhcpOptions(ctx, sess, dhcpOptionsID)
+	if err != nil {
+		return false, err
+	}
+	for _, vpc := range vpcs {
+		if aws.StringValue(vpc.VpcId) != vpcID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// preflightResetDefaultNacl checks the entry calls that --reset-defaults makes on the default network ACL.
+func preflightResetDefaultNacl(ctx context.Context, svc *ec2.EC2, nacl *ec2.NetworkAcl, check func(string, string, func() error)) {
+	dryRun := aws.Bool(true)
+	naclID := aws.StringValue(nacl.NetworkAclId)
+	for _, entry := range nacl.Entries {
+		if isCatchAllNaclEntry(entry) {
+			continue
+		}
+		check("DeleteNetworkAclEntry", fmt.Sprintf("%s %d", naclID, aws.Int64Value(entry.RuleNumber)), func() error {
+			_, err := svc.DeleteNetworkAclEntryWithContext(ctx, &ec2.DeleteNetworkAclEntryInput{
+				DryRun: dryRun, NetworkAclId: nacl.NetworkAclId, RuleNumber: entry.RuleNumber, Egress: entry.Egress})
+			return err
+		})
+	}
+	check("CreateNetworkAclEntry", naclID, func() error {
+		_, err := svc.CreateNetworkAclEntryWithContext(ctx, &ec2.CreateNetworkAclEntryInput{
+			DryRun: dryRun, NetworkAclId: nacl.NetworkAclId, RuleNumber: aws.Int64(100), Egress: aws.Bool(false),
+			Protocol: aws.String("-1"), RuleAction: aws.String(ec2.RuleActionAllow), CidrBlock: aws.String("0.0.0.0/0")})
+		return err
+	})
+}
+
+// preflightResetDefaultSg checks the rule calls that --reset-defaults makes on the default security group.
+func preflightResetDefaultSg(ctx context.Context, svc *ec2.EC2, sg *ec2.SecurityGroup, check func(string, string, func() error)) {
+	dryRun := aws.Bool(true)
+	groupID := aws.StringValue(sg.GroupId)
+	if len(sg.IpPermissions) > 0 {
+		check("RevokeSecurityGroupIngress", groupID, func() error {
+			_, err := svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{DryRun: dryRun, GroupId: sg.GroupId, IpPermissions: sg.IpPermissions})
+			return err
+		})
+	}
+	if len(sg.IpPermissionsEgress) > 0 {
+		check("RevokeSecurityGroupEgress", groupID, func() error {
+			_, err := svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{DryRun: dryRun, GroupId: sg.GroupId, IpPermissions: sg.IpPermissionsEgress})
+			return err
+		})
+	}
+	allowAll := []*ec2.IpPermission{{IpProtocol: aws.String("-1"), IpRanges: []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}}}}
+	check("AuthorizeSecurityGroupIngress", groupID, func() error {
+		_, err := svc.AuthorizeSecurityGroupIngressWithContext(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			DryRun: dryRun, GroupId: sg.GroupId,
+			IpPermissions: []*ec2.IpPermission{{IpProtocol: aws.String("-1"), UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: sg.GroupId}}}}})
+		return err
+	})
+	check("AuthorizeSecurityGroupEgress", groupID, func() error {
+		_, err := svc.AuthorizeSecurityGroupEgressWithContext(ctx, &ec2.AuthorizeSecurityGroupEgressInput{DryRun: dryRun, GroupId: sg.GroupId, IpPermissions: allowAll})
+		return err
+	})
+}
+
+// PrintPreflightResults prints the denied and failed checks and returns how many operations were denied and how
+// many checks failed.
+func PrintPreflightResults(checks []PreflightResult) (int, int) {
+	denied := 0
+	allowed := 0
+	for _, result := range checks {
+		switch result.Outcome {
+		case preflightAllowed:
+			allowed++
+		case preflightDenied:
+			denied++
+			fmt.Printf("\tDENIED  %-45s %s\n", result.Operation, result.Resource)
+		default:
+			fmt.Printf("\tERROR   %-45s %s: %s\n", result.Operation, result.Resource, result.Message)
+		}
+	}
+	failed := len(checks) - allowed - denied
+	fmt.Printf("\t%d operations allowed, %d denied, %d errors\n", allowed, denied, failed)
+	return denied, failed
+}
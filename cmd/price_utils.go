@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// embeddedPrices is the built-in price table.  The prices are on-demand list prices in USD and only need to be
+// close enough to show where the money goes.
+//
+//go:embed prices.json
+var embeddedPrices []byte
+
+// RegionPrices are the hourly prices of the billable resources in one region.
+type RegionPrices struct {
+	NatGatewayHour                  float64 `json:"nat-gateway-hour"`
+	InterfaceEndpointAzHour         float64 `json:"interface-endpoint-az-hour"`
+	GatewayLoadBalancerEndpointHour float64 `json:"gateway-load-balancer-endpoint-hour"`
+	PublicIpv4Hour                  float64 `json:"public-ipv4-hour"`
+	TransitGatewayAttachmentHour    float64 `json:"transit-gateway-attachment-hour"`
+}
+
+// PriceTable holds the prices per region, and the prices used for regions it does not list.
+type PriceTable struct {
+	Default RegionPrices            `json:"default"`
+	Regions map[string]RegionPrices `json:"regions"`
+}
+
+// LoadPriceTable returns the embedded price table, overridden by the price file at path when path is not empty.
+// The price file has the same layout as the embedded table.  A price set in its default applies to every region,
+// replacing the embedded regional price too, and a region it lists replaces the embedded entry.
+func LoadPriceTable(path string) (*PriceTable, error) {
+	table := &PriceTable{}
+	err := json.Unmarshal(embeddedPrices, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded price table: %v", err)
+	}
+	if path == "" {
+		return table, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price file %s: %v", path, err)
+	}
+	file := &PriceTable{}
+	err = json.Unmarshal(data, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse price file %s: %v", path, err)
+	}
+
+	table.Default = table.Default.overlay(file.Default)
+	for region, prices := range table.Regions {
+		table.Regions[region] = prices.overlay(file.Default)
+	}
+	for region, prices := range file.Regions {
+		table.Regions[region] = prices
+	}
+	return table, nil
+}
+
+// For returns the prices for the region.  Prices the region does not set fall back to the default prices.
+func (t *PriceTable) For(region string) RegionPrices {
+	regional, ok := t.Regions[region]
+	if !ok {
+		return t.Default
+	}
+	return t.Default.overlay(regional)
+}
+
+// overlay returns the prices with every price that other sets replaced by it.
+func (p RegionPrices) overlay(other RegionPrices) RegionPrices {
+	if other.NatGatewayHour != 0 {
+		p.NatGatewayHour = other.NatGatewayHour
+	}
+	if other.InterfaceEndpointAzHour != 0 {
+		p.InterfaceEndpointAzHour = other.InterfaceEndpointAzHour
+	}
+	if other.GatewayLoadBalancerEndpointHour != 0 {
+		p.GatewayLoadBalancerEndpointHour = other.GatewayLoadBalancerEndpointHour
+	}
+	if other.PublicIpv4Hour != 0 {
+		p.PublicIpv4Hour = other.PublicIpv4Hour
+	}
+	if other.TransitGatewayAttachmentHour != 0 {
+		p.TransitGatewayAttachmentHour = other.TransitGatewayAttachmentHour
+	}
+	return p
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ram"
+	"strings"
+)
+
+// SubnetShare is a RAM resource share that includes some of the VPC's subnets.
+type SubnetShare struct {
+	ResourceShareArn string
+	SubnetArns       []*string
+	Participants     []string
+}
+
+// ListSubnetSharesForVpc lists the RAM resource shares, owned by this account, that include any of the
+// specified subnets, together with the participant principals of each share.
+func ListSubnetSharesForVpc(ctx context.Context, sess *session.Session, subnets []*ec2.Subnet) ([]*SubnetShare, error) {
+	if len(subnets) == 0 {
+		return nil, nil
+	}
+	svc := ram.New(sess)
+
+	var subnetArns []*string
+	for _, subnet := range subnets {
+		subnetArns = append(subnetArns, subnet.SubnetArn)
+	}
+
+	var resources []*ram.Resource
+	err := svc.ListResourcesPagesWithContext(ctx, &ram.ListResourcesInput{
+		ResourceOwner: aws.String(ram.ResourceOwnerSelf),
+		ResourceType:  aws.String("ec2:Subnet"),
+		ResourceArns:  subnetArns,
+	}, func(page *ram.ListResourcesOutput, lastPage bool) bool {
+		resources = append(resources, page.Resources...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RAM shared subnets: %v", err)
+	}
+
+	sharesByArn := map[string]*SubnetShare{}
+	var shares []*SubnetShare
+	for _, resource := range resources {
+		if aws.StringValue(resource.Status) != ram.ResourceStatusAvailable {
+			continue
+		}
+		shareArn := aws.StringValue(resource.ResourceShareArn)
+		share, ok := sharesByArn[shareArn]
+		if !ok {
+			share = &SubnetShare{ResourceShareArn: shareArn}
+			sharesByArn[shareArn] = share
+			shares = append(shares, share)
+		}
+		share.SubnetArns = append(share.SubnetArns, resource.Arn)
+	}
+
+	for _, share := range shares {
+		err := svc.ListPrincipalsPagesWithContext(ctx, &ram.ListPrincipalsInput{
+			ResourceOwner:     aws.String(ram.ResourceOwnerSelf),
+			ResourceShareArns: []*string{aws.String(share.ResourceShareArn)},
+		}, func(page *ram.ListPrincipalsOutput, lastPage bool) bool {
+			for _, principal := range page.Principals {
+				share.Participants = append(share.Participants, aws.StringValue(principal.Id))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list principals of resource share %s: %v", share.ResourceShareArn, err)
+		}
+	}
+
+	return shares, nil
+}
+
+// ListParticipantEnisForVpc lists the network interfaces in the VPC that are owned by other accounts,
+// i.e. resources that RAM share participants have launched into the shared subnets.
+func ListParticipantEnisForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.NetworkInterface, error) {
+	accountID, err := GetAccountID(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return nil, err
+	}
+
+	var participantEnis []*ec2.NetworkInterface
+	for _, eni := range enis {
+		if aws.StringValue(eni.OwnerId) != accountID {
+			participantEnis = append(participantEnis, eni)
+		}
+	}
+	return participantEnis, nil
+}
+
+// PrintSubnetShares prints each share with its subnets and participants, followed by the participants' ENIs.
+func PrintSubnetShares(shares []*SubnetShare, participantEnis []*ec2.NetworkInterface) {
+	for _, share := range shares {
+		var subnetIDs []string
+		for _, arn := range share.SubnetArns {
+			subnetIDs = append(subnetIDs, arnResourceID(aws.StringValue(arn)))
+		}
+		fmt.Printf("Resource share %s shares subnets %s with: %s\n",
+			share.ResourceShareArn, strings.Join(subnetIDs, ", "), strings.Join(share.Participants, ", "))
+	}
+	for _, eni := range participantEnis {
+		fmt.Printf("Subnet %s has network interface %s owned by account %s (%s)\n",
+			aws.StringValue(eni.SubnetId), aws.StringValue(eni.NetworkInterfaceId), aws.StringValue(eni.OwnerId), aws.StringValue(eni.Description))
+	}
+}
+
+// DisassociateSubnetShares removes the VPC's subnets from the RAM resource shares.  Participants lose the
+// ability to launch into the subnets, but resources they already have there must still be removed by them.
+func DisassociateSubnetShares(ctx context.Context, sess *session.Session, shares []*SubnetShare) error {
+	fmt.Println("Disassociating subnets from RAM resource shares...")
+	// Create a new RAM client using the provided session.
+	ramSvc := ram.New(sess)
+
+	var errs MultiError
+	for _, share := range shares {
+		fmt.Printf("Disassociating %d subnets from resource share %s...\n", len(share.SubnetArns), share.ResourceShareArn)
+		err := forceAction("disassociate", "resource share", share.ResourceShareArn, func() error {
+			_, err := ramSvc.DisassociateResourceShareWithContext(ctx, &ram.DisassociateResourceShareInput{
+				ResourceShareArn: aws.String(share.ResourceShareArn),
+				ResourceArns:     share.SubnetArns,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error disassociating resource share: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Subnets disassociated from RAM resource shares.")
+	return nil
+}
+
+// arnResourceID returns the resource ID at the end of an ARN such as arn:aws:ec2:...:subnet/subnet-123.
+func arnResourceID(arn string) string {
+	if i := strings.LastIndex(arn, "/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
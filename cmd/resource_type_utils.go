@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resource types that --resource-types and --exclude-resource-types select from.  Every stage of DeleteVpc
+// belongs to one of them: the stages that clear something out of the subnets (instances, load balancers,
+// firewalls, ...) belong to subnet, and those that detach things from the VPC itself (Resolver rules, hosted
+// zones, CIDR blocks, ...) belong to vpc.
+const (
+	ResourceNatGateway    = "nat-gateway"
+	ResourceVpcEndpoint   = "vpc-endpoint"
+	ResourceEip           = "eip"
+	ResourceIgw           = "igw"
+	ResourceRouteTable    = "route-table"
+	ResourceNacl          = "nacl"
+	ResourceSecurityGroup = "security-group"
+	ResourceSubnet        = "subnet"
+	ResourceVpc           = "vpc"
+)
+
+// allResourceTypes lists the resource types in the order DeleteVpc deletes them.
+var allResourceTypes = []string{
+	ResourceVpcEndpoint,
+	ResourceNatGateway,
+	ResourceEip,
+	ResourceIgw,
+	ResourceRouteTable,
+	ResourceSecurityGroup,
+	ResourceNacl,
+	ResourceSubnet,
+	ResourceVpc,
+}
+
+// resourceTypeDependencies maps each resource type to the types that must be gone before it can be deleted.
+var resourceTypeDependencies = map[string][]string{
+	// A NAT gateway holds its Elastic IP until it is deleted.
+	ResourceEip: {ResourceNatGateway},
+	// An Internet gateway cannot be detached while public addresses in the VPC are mapped through it.
+	ResourceIgw: {ResourceNatGateway, ResourceEip},
+	// Interface endpoints reference security groups.
+	ResourceSecurityGroup: {ResourceVpcEndpoint},
+	// NAT gateways and interface endpoints put network interfaces into subnets.
+	ResourceSubnet: {ResourceNatGateway, ResourceVpcEndpoint},
+	ResourceVpc: {
+		ResourceNatGateway, ResourceVpcEndpoint, ResourceIgw, ResourceRouteTable, ResourceNacl,
+		ResourceSecurityGroup, ResourceSubnet,
+	},
+}
+
+// selectedResourceTypes is the set of resource types this run deletes; all of them unless delete narrows it down.
+var selectedResourceTypes, _ = SelectResourceTypes(nil, nil)
+
+// SelectResourceTypes settles which resource types are deleted: those in include (all of them when include is
+// empty), minus those in exclude.  It returns an error for unknown types.
+func SelectResourceTypes(include, exclude []string) (map[string]bool, error) {
+	known := map[string]bool{}
+	for _, resourceType := range allResourceTypes {
+		known[resourceType] = true
+	}
+	for _, resourceType := range append(append([]string{}, include...), exclude...) {
+		if !known[resourceType] {
+			return nil, fmt.Errorf("unknown resource type %q, expected one of: %s", resourceType, strings.Join(allResourceTypes, ", "))
+		}
+	}
+
+	selected := map[string]bool{}
+	if len(include) == 0 {
+		include = allResourceTypes
+	}
+	for _, resourceType := range include {
+		selected[resourceType] = true
+	}
+	for _, resourceType := range exclude {
+		delete(selected, resourceType)
+	}
+	return selected, nil
+}
+
+// WarnResourceTypeDependencies prints a warning for every selected resource type that depends on one that is not
+// selected, because its deletion fails if any of those remain.
+func WarnResourceTypeDependencies(selected map[string]bool) {
+	for _, resourceType := range allResourceTypes {
+		if !selected[resourceType] {
+			continue
+		}
+		var missing []string
+		for _, dependency := range resourceTypeDependencies[resourceType] {
+			if !selected[dependency] {
+				missing = append(missing, dependency)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			fmt.Printf("Warning: deleting %s depends on %s, which will not be deleted; it fails wherever those remain.\n",
+				resourceType, strings.Join(missing, ", "))
+		}
+	}
+}
+
+// typeSelected reports whether the run deletes resources of the specified type.
+func typeSelected(resourceType string) bool {
+	return selectedResourceTypes[resourceType]
+}
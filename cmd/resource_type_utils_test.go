@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what fn prints to standard output.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestSelectResourceTypes(t *testing.T) {
+	all := map[string]bool{}
+	for _, resourceType := range allResourceTypes {
+		all[resourceType] = true
+	}
+	allBut := func(excluded ...string) map[string]bool {
+		selected := map[string]bool{}
+		for resourceType := range all {
+			selected[resourceType] = true
+		}
+		for _, resourceType := range excluded {
+			delete(selected, resourceType)
+		}
+		return selected
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    map[string]bool
+		wantErr string
+	}{
+		{
+			name: "everything by default",
+			want: all,
+		},
+		{
+			name:    "include narrows down",
+			include: []string{ResourceNatGateway, ResourceVpcEndpoint},
+			want:    map[string]bool{ResourceNatGateway: true, ResourceVpcEndpoint: true},
+		},
+		{
+			name:    "exclude without include starts from everything",
+			exclude: []string{ResourceVpc, ResourceSubnet},
+			want:    allBut(ResourceVpc, ResourceSubnet),
+		},
+		{
+			name:    "exclude wins over include",
+			include: []string{ResourceNatGateway, ResourceEip},
+			exclude: []string{ResourceEip},
+			want:    map[string]bool{ResourceNatGateway: true},
+		},
+		{
+			name:    "excluding everything included selects nothing",
+			include: []string{ResourceIgw},
+			exclude: []string{ResourceIgw},
+			want:    map[string]bool{},
+		},
+		{
+			name:    "unknown included type",
+			include: []string{ResourceSubnet, "nat-gateways"},
+			wantErr: `unknown resource type "nat-gateways"`,
+		},
+		{
+			name:    "unknown excluded type",
+			exclude: []string{"VPC"},
+			wantErr: `unknown resource type "VPC"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectResourceTypes(tt.include, tt.exclude)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SelectResourceTypes() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectResourceTypes() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectResourceTypes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnResourceTypeDependencies(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		warnings []string
+	}{
+		{
+			name: "everything selected",
+		},
+		{
+			name:    "independent types",
+			include: []string{ResourceNatGateway, ResourceVpcEndpoint, ResourceRouteTable},
+		},
+		{
+			name:     "eip without nat-gateway",
+			include:  []string{ResourceEip},
+			warnings: []string{"deleting eip depends on nat-gateway"},
+		},
+		{
+			name:     "igw lists every missing dependency",
+			include:  []string{ResourceIgw},
+			warnings: []string{"deleting igw depends on eip, nat-gateway"},
+		},
+		{
+			name:    "excluding a dependency warns for every type that needs it",
+			exclude: []string{ResourceVpcEndpoint},
+			warnings: []string{
+				"deleting security-group depends on vpc-endpoint",
+				"deleting subnet depends on vpc-endpoint",
+				"deleting vpc depends on vpc-endpoint",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := SelectResourceTypes(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := captureStdout(t, func() { WarnResourceTypeDependencies(selected) })
+
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if out == "" {
+				lines = nil
+			}
+			if len(lines) != len(tt.warnings) {
+				t.Fatalf("got %d warnings, want %d:\n%s", len(lines), len(tt.warnings), out)
+			}
+			for i, warning := range tt.warnings {
+				if !strings.Contains(lines[i], warning) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, lines[i], warning)
+				}
+			}
+		})
+	}
+}
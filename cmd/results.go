@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Outcomes recorded for each operation.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeSkipped = "skipped"
+)
+
+// Exit codes returned by the tool.
+const (
+	ExitClean   = 0 // every operation succeeded
+	ExitFailed  = 1 // the run failed without completing any operation
+	ExitPartial = 2 // some operations succeeded and some failed
+)
+
+// Result is the outcome of one operation on one resource.
+type Result struct {
+	Account   string `json:"account"`
+	Region    string `json:"region"`
+	VpcID     string `json:"vpc_id,omitempty"`
+	Resource  string `json:"resource"`
+	Operation string `json:"operation"`
+	Outcome   string `json:"outcome"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ResultCollector gathers the outcome of every operation across profiles, regions and VPCs.  The tool is
+// single-threaded, so the collector tracks the account, region and VPC currently being worked on and
+// stamps each result with them.
+type ResultCollector struct {
+	account string
+	region  string
+	vpcID   string
+	Results []Result
+}
+
+// results is the collector for the current run.
+var results = &ResultCollector{}
+
+// SetScope sets the account and region that subsequent results belong to, and clears the VPC.
+func (c *ResultCollector) SetScope(account, region string) {
+	c.account = account
+	c.region = region
+	c.vpcID = ""
+}
+
+// SetVpc sets the VPC that subsequent results belong to.
+func (c *ResultCollector) SetVpc(vpcID string) {
+	c.vpcID = vpcID
+}
+
+// Record records the outcome of an operation on a resource: a success when err is nil, a skip when the
+// operation was not started because the run was interrupted, and a failure otherwise.
+func (c *ResultCollector) Record(operation, resource string, err error) {
+	result := Result{
+		Account:   c.account,
+		Region:    c.region,
+		VpcID:     c.vpcID,
+		Resource:  resource,
+		Operation: operation,
+		Outcome:   OutcomeSuccess,
+	}
+	if isInterruptedError(err) {
+		result.Outcome = OutcomeSkipped
+		result.Error = errInterrupted.Error()
+	} else if err != nil {
+		result.Outcome = OutcomeFailure
+		result.Error = err.Error()
+	}
+	c.Results = append(c.Results, result)
+	journal.Write(result)
+}
+
+// operationNouns names each operation in the messages printed when it is skipped.
+var operationNouns = map[string]string{
+	"delete":       "deletion",
+	"detach":       "detachment",
+	"disassociate": "disassociation",
+	"release":      "release",
+	"reject":       "rejection",
+	"remove":       "removal",
+	"replace":      "replacement",
+	"reset":        "reset",
+	"revoke":       "revocation",
+	"terminate":    "termination",
+}
+
+// forceAction runs act, an operation on a single resource, when --force is given, and records the outcome
+// against "<kind> <id>".  Without --force it prints the usual skip message and records a skip; when the journal
+// being resumed shows that the operation already succeeded, it does nothing.  The error, if any, names the
+// resource and is returned for the caller to decide whether to carry on.
+func forceAction(operation, kind, id string, act func() error) error {
+	resource := kind + " " + id
+	noun := operationNouns[operation]
+	if noun == "" {
+		noun = operation
+	}
+	if !forceFlag {
+		fmt.Printf("Skipping %s %s. Use the --force flag to force %s.\n", kind, noun, noun)
+		results.Skip(operation, resource, "--force not set")
+		return nil
+	}
+	if results.AlreadyDone(operation, resource) {
+		fmt.Printf("Skipping %s of %s, completed by the resumed run.\n", noun, resource)
+		return nil
+	}
+	err := act()
+	results.Record(operation, resource, err)
+	if err != nil {
+		return fmt.Errorf("%s: %v", resource, err)
+	}
+	return nil
+}
+
+// Skip records that an operation on a resource was not attempted, and why.
+func (c *ResultCollector) Skip(operation, resource, reason string) {
+	result := Result{
+		Account:   c.account,
+		Region:    c.region,
+		VpcID:     c.vpcID,
+		Resource:  resource,
+		Operation: operation,
+		Outcome:   OutcomeSkipped,
+		Error:     reason,
+	}
+	c.Results = append(c.Results, result)
+	journal.Write(result)
+}
+
+// AlreadyDone reports whether the journal being resumed shows that the operation on the resource already
+// succeeded in the current scope.
+func (c *ResultCollector) AlreadyDone(operation, resource string) bool {
+	return journal.Done(c.account, c.region, c.vpcID, operation, resource)
+}
+
+// Complete marks the current scope as finished in the journal, provided that nothing in it failed or was
+// skipped.  The marker is not a result of its own and does not show up in the summary.
+func (c *ResultCollector) Complete(resource string) {
+	for _, result := range c.Results {
+		if result.Account != c.account || result.Region != c.region || (c.vpcID != "" && result.VpcID != c.vpcID) {
+			continue
+		}
+		if result.Outcome != OutcomeSuccess {
+			return
+		}
+	}
+	journal.Write(Result{
+		Account:   c.account,
+		Region:    c.region,
+		VpcID:     c.vpcID,
+		Resource:  resource,
+		Operation: OperationComplete,
+		Outcome:   OutcomeSuccess,
+	})
+}
+
+// Count returns the number of results with the specified outcome.
+func (c *ResultCollector) Count(outcome string) int {
+	n := 0
+	for _, result := range c.Results {
+		if result.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// PrintSummary prints a table of successes, failures and skips per account, region and VPC, followed by
+// every failure.
+func (c *ResultCollector) PrintSummary() {
+	if len(c.Results) == 0 {
+		return
+	}
+
+	type counts struct{ success, failure, skipped int }
+	byScope := map[string]*counts{}
+	for _, result := range c.Results {
+		key := strings.Join([]string{result.Account, result.Region, result.VpcID}, "\t")
+		if byScope[key] == nil {
+			byScope[key] = &counts{}
+		}
+		switch result.Outcome {
+		case OutcomeSuccess:
+			byScope[key].success++
+		case OutcomeFailure:
+			byScope[key].failure++
+		case OutcomeSkipped:
+			byScope[key].skipped++
+		}
+	}
+	var keys []string
+	for key := range byScope {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println()
+	fmt.Println("Summary:")
+	fmt.Printf("%-14s %-16s %-23s %9s %9s %9s\n", "ACCOUNT", "REGION", "VPC", "SUCCEEDED", "FAILED", "SKIPPED")
+	for _, key := range keys {
+		parts := strings.Split(key, "\t")
+		n := byScope[key]
+		fmt.Printf("%-14s %-16s %-23s %9d %9d %9d\n", parts[0], parts[1], parts[2], n.success, n.failure, n.skipped)
+	}
+	fmt.Printf("%-14s %-16s %-23s %9d %9d %9d\n", "TOTAL", "", "", c.Count(OutcomeSuccess), c.Count(OutcomeFailure), c.Count(OutcomeSkipped))
+
+	if c.Count(OutcomeFailure) > 0 {
+		fmt.Println()
+		fmt.Println("Failures:")
+		for _, result := range c.Results {
+			if result.Outcome == OutcomeFailure {
+				fmt.Printf("\t%s %s %s %s %s: %s\n", result.Account, result.Region, result.VpcID, result.Operation, result.Resource, result.Error)
+			}
+		}
+	}
+}
+
+// ExitCode returns the exit code for the run, given the error (if any) that the command returned.
+func (c *ResultCollector) ExitCode(runErr error) int {
+	if Interrupted() {
+		return ExitInterrupted
+	}
+	failed := c.Count(OutcomeFailure) > 0 || runErr != nil
+	switch {
+	case !failed:
+		return ExitClean
+	case c.Count(OutcomeSuccess) > 0:
+		return ExitPartial
+	default:
+		return ExitFailed
+	}
+}
+
+// MultiError is a list of errors collected from independent units of work, such as profiles or regions.
+type MultiError []error
+
+// Error joins the messages of all the errors.
+func (m MultiError) Error() string {
+	var messages []string
+	for _, err := range m {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorOrNil returns nil when no errors were collected, so that callers never see a non-nil empty MultiError.
+func (m MultiError) ErrorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53resolver"
+	"strings"
+	"time"
+)
+
+// resolverEndpointDeleteTimeout bounds how long we wait for a Resolver endpoint to release its ENIs.
+const resolverEndpointDeleteTimeout = 10 * time.Minute
+
+// ListResolverEndpointsForVpc lists the Route 53 Resolver inbound and outbound endpoints in the specified VPC.
+func ListResolverEndpointsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*route53resolver.ResolverEndpoint, error) {
+	svc := route53resolver.New(sess)
+
+	var endpoints []*route53resolver.ResolverEndpoint
+	err := svc.ListResolverEndpointsPagesWithContext(ctx, &route53resolver.ListResolverEndpointsInput{
+		Filters: []*route53resolver.Filter{
+			{
+				Name:   aws.String("HostVPCId"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}, func(page *route53resolver.ListResolverEndpointsOutput, lastPage bool) bool {
+		endpoints = append(endpoints, page.ResolverEndpoints...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Resolver endpoints for VPC %s: %v", vpcID, err)
+	}
+
+	return endpoints, nil
+}
+
+// ListResolverRuleAssociationsForVpc lists the Route 53 Resolver rules associated with the specified VPC.
+func ListResolverRuleAssociationsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*route53resolver.ResolverRuleAssociation, error) {
+	svc := route53resolver.New(sess)
+
+	var associations []*route53resolver.ResolverRuleAssociation
+	err := svc.ListResolverRuleAssociationsPagesWithContext(ctx, &route53resolver.ListResolverRuleAssociationsInput{
+		Filters: []*route53resolver.Filter{
+			{
+				Name:   aws.String("VPCId"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	}, func(page *route53resolver.ListResolverRuleAssociationsOutput, lastPage bool) bool {
+		associations = append(associations, page.ResolverRuleAssociations...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Resolver rule associations for VPC %s: %v", vpcID, err)
+	}
+
+	return associations, nil
+}
+
+// ListHostedZonesForVpc lists the private hosted zones associated with the specified VPC.
+func ListHostedZonesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*route53.HostedZoneSummary, error) {
+	svc := route53.New(sess)
+
+	// ListHostedZonesByVPC has no Pages variant, so follow NextToken by hand.
+	input := &route53.ListHostedZonesByVPCInput{
+		VPCId:     aws.String(vpcID),
+		VPCRegion: sess.Config.Region,
+	}
+	var zones []*route53.HostedZoneSummary
+	for {
+		result, err := svc.ListHostedZonesByVPCWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list private hosted zones for VPC %s: %v", vpcID, err)
+		}
+		zones = append(zones, result.HostedZoneSummaries...)
+		if aws.StringValue(result.NextToken) == "" {
+			return zones, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+// DisassociateResolverRules disassociates the specified Resolver rules from the VPC.
+func DisassociateResolverRules(ctx context.Context, sess *session.Session, vpcID string, associations []*route53resolver.ResolverRuleAssociation) error {
+	fmt.Println("Disassociating Resolver rules...")
+	// Create a new Route 53 Resolver client using the provided session.
+	resolverSvc := route53resolver.New(sess)
+
+	var errs MultiError
+	for _, assoc := range associations {
+		fmt.Printf("Disassociating Resolver rule %s (%s) from VPC %s...\n",
+			aws.StringValue(assoc.ResolverRuleId), aws.StringValue(assoc.Name), vpcID)
+		err := forceAction("disassociate", "Resolver rule", aws.StringValue(assoc.ResolverRuleId), func() error {
+			_, err := resolverSvc.DisassociateResolverRuleWithContext(ctx, &route53resolver.DisassociateResolverRuleInput{
+				ResolverRuleId: assoc.ResolverRuleId,
+				VPCId:          aws.String(vpcID),
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error disassociating Resolver rule: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Resolver rules disassociated.")
+	return nil
+}
+
+// DeleteResolverEndpoints deletes the specified Resolver endpoints and waits until they, and their ENIs, are gone.
+func DeleteResolverEndpoints(ctx context.Context, sess *session.Session, endpoints []*route53resolver.ResolverEndpoint) error {
+	fmt.Println("Deleting Resolver endpoints...")
+	// Create a new Route 53 Resolver client using the provided session.
+	resolverSvc := route53resolver.New(sess)
+
+	var errs MultiError
+	var deleted []*string
+	for _, endpoint := range endpoints {
+		fmt.Printf("Deleting %s Resolver endpoint %s (%s)...\n",
+			aws.StringValue(endpoint.Direction), aws.StringValue(endpoint.Id), aws.StringValue(endpoint.Name))
+		err := forceAction("delete", "Resolver endpoint", aws.StringValue(endpoint.Id), func() error {
+			_, err := resolverSvc.DeleteResolverEndpointWithContext(ctx, &route53resolver.DeleteResolverEndpointInput{
+				ResolverEndpointId: endpoint.Id,
+			})
+			if err != nil {
+				return err
+			}
+			deleted = append(deleted, endpoint.Id)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error deleting Resolver endpoint: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	deadline := time.Now().Add(resolverEndpointDeleteTimeout)
+	for _, endpointID := range deleted {
+		fmt.Printf("Waiting for Resolver endpoint %s to be deleted...\n", aws.StringValue(endpointID))
+		err := waitForResolverEndpointDeleted(ctx, resolverSvc, endpointID, deadline)
+		if err != nil {
+			results.Record("wait for", "Resolver endpoint "+aws.StringValue(endpointID), err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Resolver endpoints deleted.")
+	return nil
+}
+
+// waitForResolverEndpointDeleted polls until the Resolver endpoint is gone or the deadline passes.
+func waitForResolverEndpointDeleted(ctx context.Context, resolverSvc *route53resolver.Route53Resolver, endpointID *string, deadline time.Time) error {
+	for {
+		_, err := resolverSvc.GetResolverEndpointWithContext(ctx, &route53resolver.GetResolverEndpointInput{
+			ResolverEndpointId: endpointID,
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == route53resolver.ErrCodeResourceNotFoundException {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for Resolver endpoint %s to be deleted", aws.StringValue(endpointID))
+		}
+		if err := sleepContext(ctx, 10*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// DisassociateHostedZones disassociates the VPC from the specified private hosted zones.  Route 53 refuses to
+// remove a zone's last VPC association; when deleteOrphans is set, such zones are emptied and deleted instead,
+// otherwise they are left in place.  Zones managed by another service (e.g. Cloud Map) are never touched.
+func DisassociateHostedZones(ctx context.Context, sess *session.Session, vpcID string, zones []*route53.HostedZoneSummary, deleteOrphans bool) error {
+	fmt.Println("Disassociating private hosted zones...")
+	// Create a new Route 53 client using the provided session.
+	r53Svc := route53.New(sess)
+
+	var errs MultiError
+	for _, zone := range zones {
+		zoneID := aws.StringValue(zone.HostedZoneId)
+		name := aws.StringValue(zone.Name)
+
+		if zone.Owner != nil && zone.Owner.OwningService != nil {
+			fmt.Printf("Skipping hosted zone %s (%s), it is managed by %s...\n", zoneID, name, aws.StringValue(zone.Owner.OwningService))
+			continue
+		}
+
+		detail, err := r53Svc.GetHostedZoneWithContext(ctx, &route53.GetHostedZoneInput{
+			Id: zone.HostedZoneId,
+		})
+		if err != nil {
+			fmt.Printf("Error describing hosted zone %s: %v\n", zoneID, err)
+			results.Record("list", "hosted zone "+zoneID, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		if len(detail.VPCs) <= 1 {
+			if !deleteOrphans {
+				fmt.Printf("Leaving hosted zone %s (%s), VPC %s is its last association. Use the --include-hosted-zones flag to delete it.\n", zoneID, name, vpcID)
+				continue
+			}
+			fmt.Printf("Deleting hosted zone %s (%s), VPC %s is its last association...\n", zoneID, name, vpcID)
+			err := forceAction("delete", "hosted zone", zoneID, func() error {
+				return deleteHostedZone(ctx, r53Svc, zone.HostedZoneId, name)
+			})
+			if err != nil {
+				fmt.Printf("Error deleting hosted zone: %v\n", err)
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		fmt.Printf("Disassociating hosted zone %s (%s) from VPC %s...\n", zoneID, name, vpcID)
+		err = forceAction("disassociate", "hosted zone", zoneID, func() error {
+			_, err := r53Svc.DisassociateVPCFromHostedZoneWithContext(ctx, &route53.DisassociateVPCFromHostedZoneInput{
+				HostedZoneId: zone.HostedZoneId,
+				VPC: &route53.VPC{
+					VPCId:     aws.String(vpcID),
+					VPCRegion: sess.Config.Region,
+				},
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error disassociating hosted zone: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Private hosted zones disassociated.")
+	return nil
+}
+
+// route53MaxChangesPerBatch is the most changes Route 53 accepts in one ChangeResourceRecordSets call.
+const route53MaxChangesPerBatch = 1000
+
+// deleteHostedZone deletes every record set except the zone apex SOA and NS records, then deletes the zone.
+// NS records below the apex are delegations and are deleted like any other record.
+func deleteHostedZone(ctx context.Context, r53Svc *route53.Route53, zoneID *string, zoneName string) error {
+	var changes []*route53.Change
+	err := r53Svc.ListResourceRecordSetsPagesWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId: zoneID,
+	}, func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+		for _, rrs := range page.ResourceRecordSets {
+			switch aws.StringValue(rrs.Type) {
+			case route53.RRTypeSoa, route53.RRTypeNs:
+				if sameDNSName(aws.StringValue(rrs.Name), zoneName) {
+					continue
+				}
+			}
+			changes = append(changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: rrs,
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(changes); start += route53MaxChangesPerBatch {
+		end := start + route53MaxChangesPerBatch
+		if end > len(changes) {
+			end = len(changes)
+		}
+		_, err = r53Svc.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: zoneID,
+			ChangeBatch:  &route53.ChangeBatch{Changes: changes[start:end]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = r53Svc.DeleteHostedZoneWithContext(ctx, &route53.DeleteHostedZoneInput{
+		Id: zoneID,
+	})
+	return err
+}
+
+// sameDNSName reports whether two DNS names are equal, ignoring case and the trailing dot.
+func sameDNSName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
 )
 
 // GetSession creates a new AWS session using the provided profile and region.
@@ -22,30 +24,70 @@ func GetSession(profile, region string) (*session.Session, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AWS session: %v", err)
 	}
+	// Requests already sent when the run is interrupted complete; new ones are refused.
+	sess.Handlers.Validate.PushFront(refuseWhenInterrupted)
 
 	return sess, nil
 }
 
-// IterateOverProfiles calls the provided function for each profile in the profileList.
-func IterateOverProfiles(profileList []string, fn func(string) error) error {
+// IterateOverProfiles calls the provided function for each profile in the profileList.  A failing profile does
+// not stop the others; the errors of all failing profiles are returned together.  Once the run is interrupted,
+// no further profiles are started.
+func IterateOverProfiles(ctx context.Context, profileList []string, fn func(context.Context, string) error) error {
 	fmt.Println("IterateOverProfiles called, profileList: ", profileList)
+	var errs MultiError
 	for _, profile := range profileList {
-		err := fn(profile)
+		if Interrupted() {
+			fmt.Printf("Interrupted, not starting profile %s.\n", profile)
+			errs = append(errs, fmt.Errorf("profile %s: %v", profile, errInterrupted))
+			continue
+		}
+		err := fn(ctx, profile)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("profile %s: %v", profile, err))
 		}
 	}
-	return nil
+	return errs.ErrorOrNil()
 }
 
-// IterateOverRegions calls the provided function for each region in the regionList.
-func IterateOverRegions(regionList []string, fn func(string) error) error {
+// IterateOverRegions calls the provided function for each region in the regionList.  A failing region does not
+// stop the others; the errors of all failing regions are returned together.  Once the run is interrupted, no
+// further regions are started.
+func IterateOverRegions(ctx context.Context, regionList []string, fn func(context.Context, string) error) error {
 	fmt.Println("IterateOverRegions called, regionList: ", regionList)
+	var errs MultiError
 	for _, region := range regionList {
-		err := fn(region)
+		if Interrupted() {
+			fmt.Printf("Interrupted, not starting region %s.\n", region)
+			errs = append(errs, fmt.Errorf("region %s: %v", region, errInterrupted))
+			continue
+		}
+		err := fn(ctx, region)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("region %s: %v", region, err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// GetAccountID returns the AWS account ID that the session's credentials belong to.
+func GetAccountID(ctx context.Context, sess *session.Session) (string, error) {
+	result, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %v", err)
+	}
+	return aws.StringValue(result.Account), nil
+}
+
+// AccountLabel returns the account ID for the session, falling back to the profile name when the caller
+// identity cannot be read, so that results can always be attributed to something.
+func AccountLabel(ctx context.Context, sess *session.Session, profile string) string {
+	accountID, err := GetAccountID(ctx, sess)
+	if err != nil {
+		if profile == "" {
+			return "default"
 		}
+		return profile
 	}
-	return nil
+	return accountID
 }
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+)
+
+// RevokeSgCrossReferences revokes every ingress and egress rule, in any of the specified security groups
+// (including the default group), that references one of those groups.  A group cannot be deleted while
+// another group's rules point at it, so this must run before DeleteSgs.
+func RevokeSgCrossReferences(ctx context.Context, sess *session.Session, sgs []*ec2.SecurityGroup) error {
+	fmt.Println("Revoking security group cross-references...")
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	groupIDs := map[string]bool{}
+	for _, sg := range sgs {
+		groupIDs[aws.StringValue(sg.GroupId)] = true
+	}
+
+	var errs MultiError
+	for _, sg := range sgs {
+		ingress := referencingPermissions(sg.IpPermissions, groupIDs)
+		egress := referencingPermissions(sg.IpPermissionsEgress, groupIDs)
+		if len(ingress) == 0 && len(egress) == 0 {
+			continue
+		}
+
+		fmt.Printf("Revoking %d ingress and %d egress rules referencing other groups in security group %s (%s)...\n",
+			len(ingress), len(egress), aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName))
+		err := forceAction("revoke", "security group rules of", aws.StringValue(sg.GroupId), func() error {
+			var failures []string
+			if len(ingress) > 0 {
+				_, err := ec2Svc.RevokeSecurityGroupIngressWithContext(ctx, &ec2.RevokeSecurityGroupIngressInput{
+					GroupId:       sg.GroupId,
+					IpPermissions: ingress,
+				})
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("ingress: %v", err))
+				}
+			}
+			if len(egress) > 0 {
+				_, err := ec2Svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+					GroupId:       sg.GroupId,
+					IpPermissions: egress,
+				})
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("egress: %v", err))
+				}
+			}
+			if len(failures) > 0 {
+				return fmt.Errorf("%s", strings.Join(failures, "; "))
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error revoking security group rules: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	fmt.Println("Security group cross-references revoked.")
+	return nil
+}
+
+// referencingPermissions returns the subset of the permissions that reference one of the specified groups,
+// trimmed down to just those group pairs so that CIDR and prefix list rules are left alone.
+func referencingPermissions(perms []*ec2.IpPermission, groupIDs map[string]bool) []*ec2.IpPermission {
+	var result []*ec2.IpPermission
+	for _, perm := range perms {
+		var pairs []*ec2.UserIdGroupPair
+		for _, pair := range perm.UserIdGroupPairs {
+			if groupIDs[aws.StringValue(pair.GroupId)] {
+				pairs = append(pairs, &ec2.UserIdGroupPair{
+					GroupId: pair.GroupId,
+					UserId:  pair.UserId,
+				})
+			}
+		}
+		if len(pairs) == 0 {
+			continue
+		}
+		result = append(result, &ec2.IpPermission{
+			IpProtocol:       perm.IpProtocol,
+			FromPort:         perm.FromPort,
+			ToPort:           perm.ToPort,
+			UserIdGroupPairs: pairs,
+		})
+	}
+	return result
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/efs"
+	"github.com/aws/aws-sdk-go/service/elasticache"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"strings"
+	"time"
+)
+
+// Kinds of managed-service resources that can keep a subnet from being deleted.
+const (
+	dependencyRdsSubnetGroup         = "RDS subnet group"
+	dependencyElastiCacheSubnetGroup = "ElastiCache subnet group"
+	dependencyEfsMountTarget         = "EFS mount target"
+	dependencyLambdaFunction         = "Lambda function"
+)
+
+// managedServiceEniTimeout bounds how long we wait for EFS and Lambda network interfaces to be released.
+// Lambda in particular can take well over ten minutes to let go of a detached function's interfaces.
+const managedServiceEniTimeout = 45 * time.Minute
+
+// SubnetDependency is a managed-service resource that keeps a subnet from being deleted.
+type SubnetDependency struct {
+	SubnetID string
+	Kind     string
+	Name     string
+}
+
+// String returns a printable description of the dependency.
+func (d SubnetDependency) String() string {
+	return fmt.Sprintf("%s %s", d.Kind, d.Name)
+}
+
+// ListSubnetDependenciesForVpc finds the RDS subnet groups, ElastiCache subnet groups, EFS mount targets
+// and VPC-enabled Lambda functions that use the subnets of the specified VPC.  It does not modify anything.
+func ListSubnetDependenciesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]SubnetDependency, error) {
+	var deps []SubnetDependency
+
+	rdsSvc := rds.New(sess)
+	err := rdsSvc.DescribeDBSubnetGroupsPagesWithContext(ctx, &rds.DescribeDBSubnetGroupsInput{}, func(page *rds.DescribeDBSubnetGroupsOutput, lastPage bool) bool {
+		for _, group := range page.DBSubnetGroups {
+			if aws.StringValue(group.VpcId) != vpcID {
+				continue
+			}
+			for _, subnet := range group.Subnets {
+				deps = append(deps, SubnetDependency{
+					SubnetID: aws.StringValue(subnet.SubnetIdentifier),
+					Kind:     dependencyRdsSubnetGroup,
+					Name:     aws.StringValue(group.DBSubnetGroupName),
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list RDS subnet groups for VPC %s: %v", vpcID, err)
+	}
+
+	cacheSvc := elasticache.New(sess)
+	err = cacheSvc.DescribeCacheSubnetGroupsPagesWithContext(ctx, &elasticache.DescribeCacheSubnetGroupsInput{}, func(page *elasticache.DescribeCacheSubnetGroupsOutput, lastPage bool) bool {
+		for _, group := range page.CacheSubnetGroups {
+			if aws.StringValue(group.VpcId) != vpcID {
+				continue
+			}
+			for _, subnet := range group.Subnets {
+				deps = append(deps, SubnetDependency{
+					SubnetID: aws.StringValue(subnet.SubnetIdentifier),
+					Kind:     dependencyElastiCacheSubnetGroup,
+					Name:     aws.StringValue(group.CacheSubnetGroupName),
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ElastiCache subnet groups for VPC %s: %v", vpcID, err)
+	}
+
+	efsSvc := efs.New(sess)
+	var fileSystems []*efs.FileSystemDescription
+	err = efsSvc.DescribeFileSystemsPagesWithContext(ctx, &efs.DescribeFileSystemsInput{}, func(page *efs.DescribeFileSystemsOutput, lastPage bool) bool {
+		fileSystems = append(fileSystems, page.FileSystems...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EFS file systems for VPC %s: %v", vpcID, err)
+	}
+	for _, fs := range fileSystems {
+		if aws.Int64Value(fs.NumberOfMountTargets) == 0 {
+			continue
+		}
+		mountTargets, err := efsSvc.DescribeMountTargetsWithContext(ctx, &efs.DescribeMountTargetsInput{
+			FileSystemId: fs.FileSystemId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list mount targets for EFS file system %s: %v", aws.StringValue(fs.FileSystemId), err)
+		}
+		for _, mt := range mountTargets.MountTargets {
+			if aws.StringValue(mt.VpcId) != vpcID {
+				continue
+			}
+			deps = append(deps, SubnetDependency{
+				SubnetID: aws.StringValue(mt.SubnetId),
+				Kind:     dependencyEfsMountTarget,
+				Name:     aws.StringValue(mt.MountTargetId),
+			})
+		}
+	}
+
+	lambdaSvc := lambda.New(sess)
+	err = lambdaSvc.ListFunctionsPagesWithContext(ctx, &lambda.ListFunctionsInput{}, func(page *lambda.ListFunctionsOutput, lastPage bool) bool {
+		for _, fn := range page.Functions {
+			if fn.VpcConfig == nil || aws.StringValue(fn.VpcConfig.VpcId) != vpcID {
+				continue
+			}
+			for _, subnetID := range fn.VpcConfig.SubnetIds {
+				deps = append(deps, SubnetDependency{
+					SubnetID: aws.StringValue(subnetID),
+					Kind:     dependencyLambdaFunction,
+					Name:     aws.StringValue(fn.FunctionName),
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Lambda functions for VPC %s: %v", vpcID, err)
+	}
+
+	return deps, nil
+}
+
+// PrintSubnetDependencies prints each subnet together with the managed-service resources that block its deletion.
+func PrintSubnetDependencies(deps []SubnetDependency) {
+	bySubnet := map[string][]string{}
+	var subnetIDs []string
+	for _, dep := range deps {
+		if _, ok := bySubnet[dep.SubnetID]; !ok {
+			subnetIDs = append(subnetIDs, dep.SubnetID)
+		}
+		bySubnet[dep.SubnetID] = append(bySubnet[dep.SubnetID], dep.String())
+	}
+	for _, subnetID := range subnetIDs {
+		fmt.Printf("Subnet %s is blocked by: %s\n", subnetID, strings.Join(bySubnet[subnetID], ", "))
+	}
+}
+
+// DeleteSubnetDependencies removes EFS mount targets, RDS and ElastiCache subnet groups, and detaches Lambda
+// functions from the VPC, then waits for their network interfaces to be released.  Subnet groups that are
+// still in use by a database or cache cluster will fail to delete; those clusters are not touched.
+func DeleteSubnetDependencies(ctx context.Context, sess *session.Session, vpcID string, deps []SubnetDependency) error {
+	fmt.Println("Removing managed-service subnet dependencies...")
+	rdsSvc := rds.New(sess)
+	cacheSvc := elasticache.New(sess)
+	efsSvc := efs.New(sess)
+	lambdaSvc := lambda.New(sess)
+
+	// Subnet groups and functions span several subnets, so only act on each one once.
+	seen := map[string]bool{}
+	var errs MultiError
+	var removed []SubnetDependency
+	for _, dep := range deps {
+		key := dep.Kind + "/" + dep.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch dep.Kind {
+		case dependencyEfsMountTarget:
+			fmt.Printf("Deleting EFS mount target %s...\n", dep.Name)
+		case dependencyLambdaFunction:
+			fmt.Printf("Detaching Lambda function %s from VPC %s...\n", dep.Name, vpcID)
+		default:
+			fmt.Printf("Deleting %s %s...\n", dep.Kind, dep.Name)
+		}
+		err := forceAction("remove", dep.Kind, dep.Name, func() error {
+			var err error
+			switch dep.Kind {
+			case dependencyRdsSubnetGroup:
+				_, err = rdsSvc.DeleteDBSubnetGroupWithContext(ctx, &rds.DeleteDBSubnetGroupInput{
+					DBSubnetGroupName: aws.String(dep.Name),
+				})
+			case dependencyElastiCacheSubnetGroup:
+				_, err = cacheSvc.DeleteCacheSubnetGroupWithContext(ctx, &elasticache.DeleteCacheSubnetGroupInput{
+					CacheSubnetGroupName: aws.String(dep.Name),
+				})
+			case dependencyEfsMountTarget:
+				_, err = efsSvc.DeleteMountTargetWithContext(ctx, &efs.DeleteMountTargetInput{
+					MountTargetId: aws.String(dep.Name),
+				})
+			case dependencyLambdaFunction:
+				_, err = lambdaSvc.UpdateFunctionConfigurationWithContext(ctx, &lambda.UpdateFunctionConfigurationInput{
+					FunctionName: aws.String(dep.Name),
+					VpcConfig: &lambda.VpcConfig{
+						SubnetIds:        []*string{},
+						SecurityGroupIds: []*string{},
+					},
+				})
+			}
+			if err != nil {
+				return err
+			}
+			removed = append(removed, dep)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error removing managed-service dependency: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(removed) > 0 {
+		fmt.Println("Waiting for EFS and Lambda network interfaces to be released...")
+		err := WaitForNetworkInterfacesReleased(ctx, sess, vpcID, func(eni *ec2.NetworkInterface) bool {
+			return isManagedServiceEniFor(eni, removed)
+		}, managedServiceEniTimeout)
+		if err != nil {
+			results.Record("wait for", "managed-service network interfaces", err)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	fmt.Println("Managed-service subnet dependencies removed.")
+	return nil
+}
+
+// isManagedServiceEniFor reports whether the network interface belongs to one of the removed EFS mount targets
+// ("EFS mount target for fs-... (fsmt-...)") or detached Lambda functions ("AWS Lambda VPC ENI-<function>-...").
+func isManagedServiceEniFor(eni *ec2.NetworkInterface, removed []SubnetDependency) bool {
+	description := aws.StringValue(eni.Description)
+	for _, dep := range removed {
+		switch dep.Kind {
+		case dependencyEfsMountTarget:
+			if strings.Contains(description, "("+dep.Name+")") {
+				return true
+			}
+		case dependencyLambdaFunction:
+			if aws.StringValue(eni.InterfaceType) == ec2.NetworkInterfaceTypeLambda &&
+				strings.HasPrefix(description, "AWS Lambda VPC ENI-"+dep.Name+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
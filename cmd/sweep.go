@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Delete EC2 networking resources that are not attached to any VPC",
+	Long: "Delete EC2 networking resources that are not attached to any VPC: unattached Internet gateways, " +
+		"detached virtual private gateways, unused customer gateways, unassociated Elastic IPs, unused DHCP " +
+		"options sets and empty customer-managed prefix lists",
+	RunE: sweepFunc,
+}
+
+func init() {
+	rootCmd.AddCommand(sweepCmd)
+
+	sweepCmd.Flags().StringVar(&journalPath, "journal", "", "JSON Lines file to record every operation in, so that the run can be resumed")
+	sweepCmd.Flags().StringVar(&resumePath, "resume", "", "Journal of an interrupted run; resources it completed are skipped and the rest retried")
+}
+
+func sweepFunc(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	err := OpenJournal(journalPath, resumePath)
+	if err != nil {
+		return err
+	}
+
+	err = IterateOverProfiles(ctx, profileList, func(ctx context.Context, profile string) error {
+		return IterateOverRegions(ctx, regionList, func(ctx context.Context, region string) error {
+			// Use the current profile and region to create a new session.
+			sess, err := GetSession(profile, region)
+			if err != nil {
+				results.SetScope(profile, region)
+				results.Record("create", "session", err)
+				return fmt.Errorf("failed to create session for profile %s and region %s: %v", profile, region, err)
+			}
+			account := AccountLabel(ctx, sess, profile)
+			if !AccountAllowed(account) {
+				fmt.Printf("Skipping %s (%s), account %s is not in the account allowlist.\n", profile, region, account)
+				return nil
+			}
+			results.SetScope(account, region)
+			if results.AlreadyDone(OperationComplete, "sweep region") {
+				fmt.Printf("Skipping %s (%s), completed by the resumed run.\n", profile, region)
+				return nil
+			}
+
+			orphans, err := FindOrphans(ctx, sess)
+			if err != nil {
+				results.Record("find", "orphaned resources", err)
+				return fmt.Errorf("failed to find orphaned resources: %v", err)
+			}
+			fmt.Printf("Orphaned resources in %s (%s): %d\n", profile, region, orphans.Count())
+			PrintOrphans(orphans)
+
+			err = DeleteOrphans(ctx, sess, orphans)
+			if err != nil {
+				return fmt.Errorf("failed to delete orphaned resources: %v", err)
+			}
+
+			results.Complete("sweep region")
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to IterateOverProfiles: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Orphans holds the EC2 networking resources in a region that are not associated with any VPC.
+type Orphans struct {
+	Igws             []*ec2.InternetGateway
+	VpnGateways      []*ec2.VpnGateway
+	CustomerGateways []*ec2.CustomerGateway
+	Eips             []*ec2.Address
+	DhcpOptions      []*ec2.DhcpOptions
+	PrefixLists      []*ec2.ManagedPrefixList
+}
+
+// Count returns the total number of orphaned resources.
+func (o *Orphans) Count() int {
+	return len(o.Igws) + len(o.VpnGateways) + len(o.CustomerGateways) + len(o.Eips) + len(o.DhcpOptions) + len(o.PrefixLists)
+}
+
+// FindOrphans finds unattached Internet gateways, detached virtual private gateways, customer gateways with no
+// VPN connection, unassociated Elastic IPs, DHCP options sets used by no VPC, and empty, unreferenced
+// customer-managed prefix lists owned by the account.
+func FindOrphans(ctx context.Context, sess *session.Session) (*Orphans, error) {
+	svc := ec2.New(sess)
+	orphans := &Orphans{}
+
+	err := svc.DescribeInternetGatewaysPagesWithContext(ctx, &ec2.DescribeInternetGatewaysInput{}, func(page *ec2.DescribeInternetGatewaysOutput, lastPage bool) bool {
+		for _, igw := range page.InternetGateways {
+			if len(igw.Attachments) == 0 {
+				orphans.Igws = append(orphans.Igws, igw)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Internet gateways: %v", err)
+	}
+
+	// Gateways referenced by a live VPN connection are still in use even without a VPC.
+	vpnConnections, err := svc.DescribeVpnConnectionsWithContext(ctx, &ec2.DescribeVpnConnectionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VPN connections: %v", err)
+	}
+	inUse := map[string]bool{}
+	for _, conn := range vpnConnections.VpnConnections {
+		if aws.StringValue(conn.State) == ec2.VpnStateDeleted {
+			continue
+		}
+		inUse[aws.StringValue(conn.VpnGatewayId)] = true
+		inUse[aws.StringValue(conn.CustomerGatewayId)] = true
+	}
+
+	vgws, err := svc.DescribeVpnGatewaysWithContext(ctx, &ec2.DescribeVpnGatewaysInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual private gateways: %v", err)
+	}
+	for _, vgw := range vgws.VpnGateways {
+		if aws.StringValue(vgw.State) != ec2.VpnStateAvailable || inUse[aws.StringValue(vgw.VpnGatewayId)] {
+			continue
+		}
+		attached := false
+		for _, attachment := range vgw.VpcAttachments {
+			if aws.StringValue(attachment.State) != ec2.AttachmentStatusDetached {
+				attached = true
+			}
+		}
+		if !attached {
+			orphans.VpnGateways = append(orphans.VpnGateways, vgw)
+		}
+	}
+
+	cgws, err := svc.DescribeCustomerGatewaysWithContext(ctx, &ec2.DescribeCustomerGatewaysInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer gateways: %v", err)
+	}
+	for _, cgw := range cgws.CustomerGateways {
+		if aws.StringValue(cgw.State) == "available" && !inUse[aws.StringValue(cgw.CustomerGatewayId)] {
+			orphans.CustomerGateways = append(orphans.CustomerGateways, cgw)
+		}
+	}
+
+	// DescribeAddresses is not paginated; it returns every address in one response.
+	eips, err := svc.DescribeAddressesWithContext(ctx, &ec2.DescribeAddressesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("domain"),
+				Values: []*string{aws.String("vpc")},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Elastic IPs: %v", err)
+	}
+	for _, eip := range eips.Addresses {
+		if eip.AssociationId == nil {
+			orphans.Eips = append(orphans.Eips, eip)
+		}
+	}
+
+	vpcs, err := ListVpcs(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	usedDhcpOptions := map[string]bool{}
+	for _, vpc := range vpcs {
+		usedDhcpOptions[aws.StringValue(vpc.DhcpOptionsId)] = true
+	}
+	dhcpOptions, err := svc.DescribeDhcpOptionsWithContext(ctx, &ec2.DescribeDhcpOptionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DHCP options sets: %v", err)
+	}
+	for _, options := range dhcpOptions.DhcpOptions {
+		if !usedDhcpOptions[aws.StringValue(options.DhcpOptionsId)] && !isAwsProvidedDhcpOptions(options) {
+			orphans.DhcpOptions = append(orphans.DhcpOptions, options)
+		}
+	}
+
+	accountID, err := GetAccountID(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+	var prefixLists []*ec2.ManagedPrefixList
+	err = svc.DescribeManagedPrefixListsPagesWithContext(ctx, &ec2.DescribeManagedPrefixListsInput{}, func(page *ec2.DescribeManagedPrefixListsOutput, lastPage bool) bool {
+		prefixLists = append(prefixLists, page.PrefixLists...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list managed prefix lists: %v", err)
+	}
+	for _, pl := range prefixLists {
+		// Skip AWS-managed lists and lists shared with us from other accounts.
+		if aws.StringValue(pl.OwnerId) != accountID {
+			continue
+		}
+		entries, err := svc.GetManagedPrefixListEntriesWithContext(ctx, &ec2.GetManagedPrefixListEntriesInput{
+			PrefixListId: pl.PrefixListId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list entries of prefix list %s: %v", aws.StringValue(pl.PrefixListId), err)
+		}
+		if len(entries.Entries) > 0 {
+			continue
+		}
+		associations, err := svc.GetManagedPrefixListAssociationsWithContext(ctx, &ec2.GetManagedPrefixListAssociationsInput{
+			PrefixListId: pl.PrefixListId,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list associations of prefix list %s: %v", aws.StringValue(pl.PrefixListId), err)
+		}
+		if len(associations.PrefixListAssociations) == 0 {
+			orphans.PrefixLists = append(orphans.PrefixLists, pl)
+		}
+	}
+
+	return orphans, nil
+}
+
+// PrintOrphans prints each orphaned resource.
+func PrintOrphans(orphans *Orphans) {
+	for _, igw := range orphans.Igws {
+		fmt.Printf("\tInternet gateway %s (%s)\n", aws.StringValue(igw.InternetGatewayId), getNameTag(igw.Tags))
+	}
+	for _, vgw := range orphans.VpnGateways {
+		fmt.Printf("\tVirtual private gateway %s (%s)\n", aws.StringValue(vgw.VpnGatewayId), getNameTag(vgw.Tags))
+	}
+	for _, cgw := range orphans.CustomerGateways {
+		fmt.Printf("\tCustomer gateway %s (%s, %s)\n", aws.StringValue(cgw.CustomerGatewayId), aws.StringValue(cgw.IpAddress), getNameTag(cgw.Tags))
+	}
+	for _, eip := range orphans.Eips {
+		fmt.Printf("\tElastic IP %s (%s)\n", aws.StringValue(eip.PublicIp), aws.StringValue(eip.AllocationId))
+	}
+	for _, options := range orphans.DhcpOptions {
+		fmt.Printf("\tDHCP options set %s (%s)\n", aws.StringValue(options.DhcpOptionsId), getNameTag(options.Tags))
+	}
+	for _, pl := range orphans.PrefixLists {
+		fmt.Printf("\tManaged prefix list %s (%s)\n", aws.StringValue(pl.PrefixListId), aws.StringValue(pl.PrefixListName))
+	}
+}
+
+// DeleteOrphans deletes the orphaned resources.  Every resource is attempted; the failures are collected and
+// returned together.
+func DeleteOrphans(ctx context.Context, sess *session.Session, orphans *Orphans) error {
+	// Create a new EC2 client using the provided session.
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+
+	for _, igw := range orphans.Igws {
+		fmt.Printf("Deleting Internet gateway %s (%s)...\n", aws.StringValue(igw.InternetGatewayId), getNameTag(igw.Tags))
+		err := forceDelete("Internet gateway", aws.StringValue(igw.InternetGatewayId), func() error {
+			_, err := ec2Svc.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{
+				InternetGatewayId: igw.InternetGatewayId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, vgw := range orphans.VpnGateways {
+		fmt.Printf("Deleting virtual private gateway %s (%s)...\n", aws.StringValue(vgw.VpnGatewayId), getNameTag(vgw.Tags))
+		err := forceDelete("virtual private gateway", aws.StringValue(vgw.VpnGatewayId), func() error {
+			_, err := ec2Svc.DeleteVpnGatewayWithContext(ctx, &ec2.DeleteVpnGatewayInput{
+				VpnGatewayId: vgw.VpnGatewayId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, cgw := range orphans.CustomerGateways {
+		fmt.Printf("Deleting customer gateway %s (%s)...\n", aws.StringValue(cgw.CustomerGatewayId), getNameTag(cgw.Tags))
+		err := forceDelete("customer gateway", aws.StringValue(cgw.CustomerGatewayId), func() error {
+			_, err := ec2Svc.DeleteCustomerGatewayWithContext(ctx, &ec2.DeleteCustomerGatewayInput{
+				CustomerGatewayId: cgw.CustomerGatewayId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, eip := range orphans.Eips {
+		fmt.Printf("Releasing Elastic IP %s (%s)...\n", aws.StringValue(eip.PublicIp), aws.StringValue(eip.AllocationId))
+		err := forceDelete("Elastic IP", aws.StringValue(eip.PublicIp), func() error {
+			_, err := ec2Svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{
+				AllocationId: eip.AllocationId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, options := range orphans.DhcpOptions {
+		fmt.Printf("Deleting DHCP options set %s (%s)...\n", aws.StringValue(options.DhcpOptionsId), getNameTag(options.Tags))
+		err := forceDelete("DHCP options set", aws.StringValue(options.DhcpOptionsId), func() error {
+			_, err := ec2Svc.DeleteDhcpOptionsWithContext(ctx, &ec2.DeleteDhcpOptionsInput{
+				DhcpOptionsId: options.DhcpOptionsId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, pl := range orphans.PrefixLists {
+		fmt.Printf("Deleting managed prefix list %s (%s)...\n", aws.StringValue(pl.PrefixListId), aws.StringValue(pl.PrefixListName))
+		err := forceDelete("managed prefix list", aws.StringValue(pl.PrefixListId), func() error {
+			_, err := ec2Svc.DeleteManagedPrefixListWithContext(ctx, &ec2.DeleteManagedPrefixListInput{
+				PrefixListId: pl.PrefixListId,
+			})
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// forceDelete deletes one resource through forceAction.  Errors are printed and returned.
+func forceDelete(kind, id string, del func() error) error {
+	err := forceAction("delete", kind, id, del)
+	if err != nil {
+		fmt.Printf("Error deleting %s: %v\n", kind, err)
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"time"
+)
+
+var (
+	// olderThan, when set, limits the run to VPCs created at least this long ago.
+	olderThan time.Duration
+	// createdBefore, when set, limits the run to VPCs created before this date or time.
+	createdBefore string
+	// creationTag is the tag key whose value, when present, is taken as the VPC's creation time.
+	creationTag string
+
+	// ageCutoff is the latest creation time a VPC may have to be selected; zero selects every VPC.
+	ageCutoff time.Time
+)
+
+// creationTimeLayouts are the formats accepted for --created-before and for the creation tag.
+var creationTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// parseCreationTime parses a time in one of creationTimeLayouts.  Times without a zone are UTC.
+func parseCreationTime(value string) (time.Time, error) {
+	for _, layout := range creationTimeLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a date such as 2006-01-02 or an RFC 3339 time", value)
+}
+
+// SetAgeCutoff computes ageCutoff from --older-than and --created-before.  When both are given, a VPC must satisfy
+// both, so the earlier of the two wins.
+func SetAgeCutoff(now time.Time) error {
+	ageCutoff = time.Time{}
+	if olderThan < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+	if olderThan > 0 {
+		ageCutoff = now.Add(-olderThan)
+	}
+	if createdBefore != "" {
+		t, err := parseCreationTime(createdBefore)
+		if err != nil {
+			return fmt.Errorf("--created-before: %v", err)
+		}
+		if ageCutoff.IsZero() || t.Before(ageCutoff) {
+			ageCutoff = t
+		}
+	}
+	return nil
+}
+
+// VpcCreationTime returns when the VPC was created, along with where that time came from.  EC2 does not record
+// the creation time of a VPC, so it is taken from the creation tag if the VPC has one, or else from the earliest
+// creation time of its NAT gateways and VPC endpoints, which the VPC cannot be younger than.  The time is zero
+// when neither is available, or when the creation tag cannot be parsed.
+func VpcCreationTime(ctx context.Context, sess *session.Session, vpc *ec2.Vpc) (time.Time, string, error) {
+	vpcID := aws.StringValue(vpc.VpcId)
+
+	for _, tag := range vpc.Tags {
+		if creationTag != "" && aws.StringValue(tag.Key) == creationTag {
+			t, err := parseCreationTime(aws.StringValue(tag.Value))
+			if err != nil {
+				fmt.Printf("Warning: tag %s of VPC %s: %v\n", creationTag, vpcID, err)
+				return time.Time{}, "", nil
+			}
+			return t, "tag " + creationTag, nil
+		}
+	}
+
+	var earliest time.Time
+	var source string
+	natGateways, err := ListNatGatewaysForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	for _, natGw := range natGateways {
+		if natGw.CreateTime != nil && (earliest.IsZero() || natGw.CreateTime.Before(earliest)) {
+			earliest = *natGw.CreateTime
+			source = "NAT gateway " + aws.StringValue(natGw.NatGatewayId)
+		}
+	}
+
+	vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	for _, vpcEndpoint := range vpcEndpoints {
+		if vpcEndpoint.CreationTimestamp != nil && (earliest.IsZero() || vpcEndpoint.CreationTimestamp.Before(earliest)) {
+			earliest = *vpcEndpoint.CreationTimestamp
+			source = "VPC endpoint " + aws.StringValue(vpcEndpoint.VpcEndpointId)
+		}
+	}
+
+	return earliest, source, nil
+}
+
+// SelectVpcsByAge returns the VPCs created before ageCutoff.  VPCs whose creation time cannot be determined are
+// left out, so that nothing is selected by accident.
+func SelectVpcsByAge(ctx context.Context, sess *session.Session, vpcs []*ec2.Vpc) ([]*ec2.Vpc, error) {
+	if ageCutoff.IsZero() {
+		return vpcs, nil
+	}
+
+	var selected []*ec2.Vpc
+	for _, vpc := range vpcs {
+		created, source, err := VpcCreationTime(ctx, sess, vpc)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case created.IsZero():
+			fmt.Printf("Skipping VPC %s, its creation time is unknown.\n", *vpc.VpcId)
+		case !created.Before(ageCutoff):
+			fmt.Printf("Skipping VPC %s, created %s (from %s).\n", *vpc.VpcId, created.Format(time.RFC3339), source)
+		default:
+			selected = append(selected, vpc)
+		}
+	}
+	return selected, nil
+}
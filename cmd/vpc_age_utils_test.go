@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCreationTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-01T14:30", want: time.Date(2024, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{value: "2024-03-01T14:30:15Z", want: time.Date(2024, 3, 1, 14, 30, 15, 0, time.UTC)},
+		{value: "2024-03-01T14:30:15+02:00", want: time.Date(2024, 3, 1, 12, 30, 15, 0, time.UTC)},
+		{value: "", wantErr: true},
+		{value: "yesterday", wantErr: true},
+		{value: "01/03/2024", wantErr: true},
+		{value: "2024-13-01", wantErr: true},
+		{value: "2024-03-01 14:30", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseCreationTime(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCreationTime(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseCreationTime(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAgeCutoff(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		olderThan     time.Duration
+		createdBefore string
+		want          time.Time
+		wantErr       bool
+	}{
+		{
+			name: "no filter",
+		},
+		{
+			name:      "older than",
+			olderThan: 72 * time.Hour,
+			want:      time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "created before",
+			createdBefore: "2024-03-01",
+			want:          time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "created before is earlier and wins",
+			olderThan:     24 * time.Hour,
+			createdBefore: "2024-03-01",
+			want:          time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "older than is earlier and wins",
+			olderThan:     30 * 24 * time.Hour,
+			createdBefore: "2024-03-01",
+			want:          time.Date(2024, 2, 9, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "negative duration",
+			olderThan: -time.Hour,
+			wantErr:   true,
+		},
+		{
+			name:          "negative duration with created before",
+			olderThan:     -time.Hour,
+			createdBefore: "2024-03-01",
+			wantErr:       true,
+		},
+		{
+			name:          "invalid created before",
+			createdBefore: "last week",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			olderThan, createdBefore = tt.olderThan, tt.createdBefore
+			defer func() { olderThan, createdBefore, ageCutoff = 0, "", time.Time{} }()
+
+			err := SetAgeCutoff(now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetAgeCutoff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !ageCutoff.Equal(tt.want) {
+				t.Errorf("ageCutoff = %v, want %v", ageCutoff, tt.want)
+			}
+		})
+	}
+}
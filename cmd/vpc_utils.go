@@ -1,17 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"strings"
+	"time"
 )
 
+// vpcEndpointDeleteTimeout bounds how long we wait for VPC endpoints to finish deleting.
+const vpcEndpointDeleteTimeout = 10 * time.Minute
+
 // ListVpcs lists all VPCs in the specified session.
-func ListVpcs(sess *session.Session) ([]*ec2.Vpc, error) {
+func ListVpcs(ctx context.Context, sess *session.Session) ([]*ec2.Vpc, error) {
 	svc := ec2.New(sess)
 
-	result, err := svc.DescribeVpcs(nil)
+	result, err := svc.DescribeVpcsWithContext(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list VPCs: %v", err)
 	}
@@ -20,7 +27,7 @@ func ListVpcs(sess *session.Session) ([]*ec2.Vpc, error) {
 }
 
 // ListSubnetsForVpc lists all subnets for the specified VPC ID in the specified session.
-func ListSubnetsForVpc(sess *session.Session, vpcID string) ([]*ec2.Subnet, error) {
+func ListSubnetsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.Subnet, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.DescribeSubnetsInput{
@@ -32,7 +39,7 @@ func ListSubnetsForVpc(sess *session.Session, vpcID string) ([]*ec2.Subnet, erro
 		},
 	}
 
-	result, err := svc.DescribeSubnets(input)
+	result, err := svc.DescribeSubnetsWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subnets for VPC %s: %v", vpcID, err)
 	}
@@ -41,7 +48,7 @@ func ListSubnetsForVpc(sess *session.Session, vpcID string) ([]*ec2.Subnet, erro
 }
 
 // ListNatGatewaysForVpc lists all NAT gateways for the specified VPC ID in the specified session.
-func ListNatGatewaysForVpc(sess *session.Session, vpcID string) ([]*ec2.NatGateway, error) {
+func ListNatGatewaysForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.NatGateway, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.DescribeNatGatewaysInput{
@@ -53,7 +60,7 @@ func ListNatGatewaysForVpc(sess *session.Session, vpcID string) ([]*ec2.NatGatew
 		},
 	}
 
-	result, err := svc.DescribeNatGateways(input)
+	result, err := svc.DescribeNatGatewaysWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list NAT gateways for VPC %s: %v", vpcID, err)
 	}
@@ -62,7 +69,7 @@ func ListNatGatewaysForVpc(sess *session.Session, vpcID string) ([]*ec2.NatGatew
 }
 
 // ListVpcEndpointsForVpc lists all VPC endpoints for the specified VPC ID in the specified session.
-func ListVpcEndpointsForVpc(sess *session.Session, vpcID string) ([]*ec2.VpcEndpoint, error) {
+func ListVpcEndpointsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.VpcEndpoint, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.DescribeVpcEndpointsInput{
@@ -74,7 +81,7 @@ func ListVpcEndpointsForVpc(sess *session.Session, vpcID string) ([]*ec2.VpcEndp
 		},
 	}
 
-	result, err := svc.DescribeVpcEndpoints(input)
+	result, err := svc.DescribeVpcEndpointsWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list VPC endpoints for VPC %s: %v", vpcID, err)
 	}
@@ -82,34 +89,54 @@ func ListVpcEndpointsForVpc(sess *session.Session, vpcID string) ([]*ec2.VpcEndp
 	return result.VpcEndpoints, nil
 }
 
-// ListEipsForVpc lists all Elastic IP addresses for the specified VPC ID in the specified session.
-func ListEipsForVpc(sess *session.Session, vpcID string) ([]*ec2.Address, error) {
+// ListEipsForVpc lists all Elastic IP addresses for the specified VPC ID in the specified session.  Addresses
+// carry no VPC ID, so they are matched by the network interfaces of the VPC they are associated with.
+func ListEipsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.Address, error) {
 	svc := ec2.New(sess)
 
-	input := &ec2.DescribeAddressesInput{
-		Filters: []*ec2.Filter{
-			{
-				Name:   aws.String("domain"),
-				Values: []*string{aws.String("vpc")},
-			},
-			// constrain to the specified vpcID
-			{
-				Name:   aws.String("network-interface-id"),
-				Values: []*string{aws.String(vpcID)},
-			},
-		},
-	}
-
-	result, err := svc.DescribeAddresses(input)
+	enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Elastic IPs for VPC %s: %v", vpcID, err)
 	}
 
-	return result.Addresses, nil
+	var eniIDs []*string
+	for _, eni := range enis {
+		eniIDs = append(eniIDs, eni.NetworkInterfaceId)
+	}
+
+	// A filter takes at most 200 values.
+	var addresses []*ec2.Address
+	for start := 0; start < len(eniIDs); start += 200 {
+		end := start + 200
+		if end > len(eniIDs) {
+			end = len(eniIDs)
+		}
+		input := &ec2.DescribeAddressesInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("domain"),
+					Values: []*string{aws.String("vpc")},
+				},
+				// constrain to the network interfaces of the specified vpcID
+				{
+					Name:   aws.String("network-interface-id"),
+					Values: eniIDs[start:end],
+				},
+			},
+		}
+
+		result, err := svc.DescribeAddressesWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Elastic IPs for VPC %s: %v", vpcID, err)
+		}
+		addresses = append(addresses, result.Addresses...)
+	}
+
+	return addresses, nil
 }
 
 // ListIgwsForVpc lists all Internet gateways for the specified VPC ID in the specified session.
-func ListIgwsForVpc(sess *session.Session, vpcID string) ([]*ec2.InternetGateway, error) {
+func ListIgwsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.InternetGateway, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.DescribeInternetGatewaysInput{
@@ -121,7 +148,7 @@ func ListIgwsForVpc(sess *session.Session, vpcID string) ([]*ec2.InternetGateway
 		},
 	}
 
-	result, err := svc.DescribeInternetGateways(input)
+	result, err := svc.DescribeInternetGatewaysWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Internet gateways for VPC %s: %v", vpcID, err)
 	}
@@ -129,205 +156,668 @@ func ListIgwsForVpc(sess *session.Session, vpcID string) ([]*ec2.InternetGateway
 	return result.InternetGateways, nil
 }
 
-func DeleteAllVpcs(sess *session.Session, force bool) error {
-	vpcs, err := ListVpcs(sess)
+func DeleteAllVpcs(ctx context.Context, sess *session.Session, force bool) error {
+	vpcs, err := ListVpcs(ctx, sess)
 	if err != nil {
+		results.Record("list", "VPCs", err)
 		return fmt.Errorf("failed to list VPCs: %v", err)
 	}
+	vpcs, err = SelectVpcsByAge(ctx, sess, SelectVpcs(vpcs))
+	if err != nil {
+		results.Record("list", "VPC creation times", err)
+		return fmt.Errorf("failed to determine VPC creation times: %v", err)
+	}
 
+	// A VPC that fails to delete does not stop the others.
+	var errs MultiError
 	for _, vpc := range vpcs {
-		err := DeleteVpc(sess, vpc, force)
-		if err != nil && !ignoreErrors {
-			return err
+		results.SetVpc(aws.StringValue(vpc.VpcId))
+		if Interrupted() {
+			fmt.Printf("Interrupted, not starting VPC %s.\n", *vpc.VpcId)
+			results.Skip("delete", "VPC", errInterrupted.Error())
+			continue
+		}
+		// Only a kept VPC can still be here after a resumed run completed it.
+		if results.AlreadyDone(OperationComplete, "VPC") {
+			fmt.Printf("Skipping VPC %s, completed by the resumed run.\n", *vpc.VpcId)
+			continue
+		}
+		err := DeleteVpc(ctx, sess, vpc, force)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("VPC %s: %v", aws.StringValue(vpc.VpcId), err))
+			continue
 		}
+		results.Complete("VPC")
 		fmt.Println("Deleted VPC", *vpc.VpcId)
 	}
+	results.SetVpc("")
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
 // DeleteVpc deletes the specified VPC, along with all associated resources, in the specified session.
 // change to vpc pointer
-func DeleteVpc(sess *session.Session, vpc *ec2.Vpc, force bool) error {
+func DeleteVpc(ctx context.Context, sess *session.Session, vpc *ec2.Vpc, force bool) error {
 	vpcID := *vpc.VpcId
+	// With --ignore-errors, failed steps are collected and returned once every step has been attempted.
+	var errs MultiError
 	fmt.Println("Deleting VPC", vpcID)
 	// List all associated resources for the VPC.
-	subnets, err := ListSubnetsForVpc(sess, vpcID)
+	subnets, err := ListSubnetsForVpc(ctx, sess, vpcID)
+
+	if err != nil {
+		results.Record("list", "subnets", err)
+		fmt.Printf("failed to list subnets for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	natGateways, err := ListNatGatewaysForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "NAT gateways", err)
+		fmt.Printf("failed to list NAT gateways for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	igws, err := ListIgwsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "Internet gateways", err)
+		fmt.Printf("failed to list Internet gateways for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
 
+	vpcEndpoints, err := ListVpcEndpointsForVpc(ctx, sess, vpcID)
 	if err != nil {
-		fmt.Print("failed to list subnets for VPC %s: %v", vpcID, err)
+		results.Record("list", "VPC endpoints", err)
+		fmt.Printf("failed to list VPC endpoints for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	natGateways, err := ListNatGatewaysForVpc(sess, vpcID)
+	routeTables, err := ListRouteTablesForVpc(ctx, sess, vpc)
 	if err != nil {
-		fmt.Print("failed to list NAT gateways for VPC %s: %v", vpcID, err)
+		results.Record("list", "route tables", err)
+		fmt.Printf("failed to list route tables for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	igws, err := ListIgwsForVpc(sess, vpcID)
+	eips, err := ListEipsForVpc(ctx, sess, vpcID)
 	if err != nil {
-		fmt.Print("failed to list Internet gateways for VPC %s: %v", vpcID, err)
+		results.Record("list", "Elastic IPs", err)
+		fmt.Printf("failed to list Elastic IPs for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	vpcEndpoints, err := ListVpcEndpointsForVpc(sess, vpcID)
+	nacls, err := ListNaclsForVpc(ctx, sess, vpc)
 	if err != nil {
-		fmt.Print("failed to list VPC endpoints for VPC %s: %v", vpcID, err)
+		results.Record("list", "network ACLs", err)
+		fmt.Printf("failed to list network ACLs for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	// delete all non-default route tables
-	routeTables, err := ListRouteTablesForVpc(sess, vpc)
+	sgs, err := ListSgsForVpc(ctx, sess, vpcID)
 	if err != nil {
-		fmt.Print("failed to list route tables for VPC %s: %v", vpcID, err)
+		results.Record("list", "security groups", err)
+		fmt.Printf("failed to list security groups for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	eips, err := ListEipsForVpc(sess, vpcID)
+	defaultSgID, err := DefaultSgIDForVpc(ctx, sess, vpcID)
 	if err != nil {
-		fmt.Print("failed to list Elastic IPs for VPC %s: %v", vpcID, err)
+		results.Record("list", "default security group", err)
+		fmt.Printf("failed to find the default security group for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	nacls, err := ListNaclsForVpc(sess, vpc)
+	// Prefix lists referenced only by the security groups and route tables being deleted go with them.
+	prefixLists, err := ListPrefixListsOnlyUsedBy(ctx, sess, sgs, defaultSgID, routeTables)
 	if err != nil {
-		fmt.Print("failed to list network ACLs for VPC %s: %v", vpcID, err)
+		results.Record("list", "prefix lists", err)
+		fmt.Printf("failed to list prefix lists for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	sgs, err := ListSgsForVpc(sess, vpcID)
+	flowLogs, err := ListFlowLogsForVpc(ctx, sess, vpcID, subnets)
 	if err != nil {
-		fmt.Print("failed to list security groups for VPC %s: %v", vpcID, err)
+		results.Record("list", "flow logs", err)
+		fmt.Printf("failed to list flow logs for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	if len(vpcEndpoints) > 0 {
+	if len(flowLogs) > 0 && typeSelected(ResourceVpc) {
+		fmt.Printf("Deleting %d flow logs in VPC %s...\n", len(flowLogs), vpcID)
+		err := DeleteFlowLogs(ctx, sess, flowLogs)
+		if err != nil {
+			fmt.Printf("failed to delete flow logs for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Traffic mirror sessions and targets reference ENIs and load balancers in the VPC.
+	mirrorSessions, mirrorTargets, err := ListTrafficMirrorResourcesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		// The listing needs read access to ELB as well.  As with the subnet dependency scan, a failure is a warning.
+		fmt.Printf("Warning: failed to list traffic mirror resources for VPC %s: %v\n", vpcID, err)
+	}
+
+	if (len(mirrorSessions) > 0 || len(mirrorTargets) > 0) && typeSelected(ResourceSubnet) {
+		fmt.Printf("Deleting %d traffic mirror sessions and %d traffic mirror targets in VPC %s...\n", len(mirrorSessions), len(mirrorTargets), vpcID)
+		err := DeleteTrafficMirrorResources(ctx, sess, mirrorSessions, mirrorTargets)
+		if err != nil {
+			fmt.Printf("failed to delete traffic mirror resources for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Instances block the deletion of every subnet they live in.
+	if includeInstances && typeSelected(ResourceSubnet) {
+		instances, err := ListInstancesForVpc(ctx, sess, vpcID)
+		if err != nil {
+			results.Record("list", "instances", err)
+			fmt.Printf("failed to list instances for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+		if len(instances) > 0 {
+			fmt.Printf("Terminating %d instances in VPC %s...\n", len(instances), vpcID)
+			err := TerminateInstances(ctx, sess, instances)
+			if err != nil {
+				fmt.Printf("failed to terminate instances for VPC %s: %v\n", vpcID, err)
+				errs = append(errs, err)
+				if !ignoreErrors {
+					return err
+				}
+			}
+		}
+	}
+
+	// An endpoint service must be deleted before the load balancers behind it.
+	endpointServices, err := ListEndpointServicesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		// The listing needs read access to ELB as well.  As with the subnet dependency scan, a failure is a warning.
+		fmt.Printf("Warning: failed to list VPC endpoint services for VPC %s: %v\n", vpcID, err)
+	}
+
+	if len(endpointServices) > 0 && typeSelected(ResourceSubnet) {
+		fmt.Printf("Deleting %d VPC endpoint services in VPC %s...\n", len(endpointServices), vpcID)
+		err := DeleteEndpointServices(ctx, sess, endpointServices)
+		if err != nil {
+			fmt.Printf("failed to delete VPC endpoint services for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Load balancers leave requester-managed ENIs behind that block subnet and security group deletion.
+	if includeLoadBalancers && typeSelected(ResourceSubnet) {
+		err := DeleteLoadBalancersForVpc(ctx, sess, vpcID)
+		if err != nil {
+			fmt.Printf("failed to delete load balancers for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Flush the main route table before the gateways and endpoints its routes point at are deleted.
+	if len(routeTables) > 0 && typeSelected(ResourceRouteTable) {
+		err := FlushMainRouteTableRoutes(ctx, sess, routeTables)
+		if err != nil {
+			fmt.Printf("failed to flush main route table for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Network Firewall owns its endpoints, and route tables point at them, so firewalls go first.
+	firewalls, err := ListFirewallsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		// Network Firewall is not offered in every region, and the caller may not be allowed to read it.  As with
+		// the subnet dependency scan, that is only a warning: a firewall left behind still fails the subnet delete.
+		fmt.Printf("Warning: failed to list firewalls for VPC %s: %v\n", vpcID, err)
+	}
+
+	if len(firewalls) > 0 && typeSelected(ResourceSubnet) {
+		// The firewall's endpoints are deleted along with the firewall.
+		firewallEndpoints := FirewallEndpointIds(firewalls)
+		var remaining []*ec2.VpcEndpoint
+		for _, vpcEndpoint := range vpcEndpoints {
+			if !firewallEndpoints[aws.StringValue(vpcEndpoint.VpcEndpointId)] {
+				remaining = append(remaining, vpcEndpoint)
+			}
+		}
+		vpcEndpoints = remaining
+
+		fmt.Printf("Deleting %d Network Firewall firewalls in VPC %s...\n", len(firewalls), vpcID)
+		err := DeleteFirewalls(ctx, sess, firewalls, includeFirewallPolicies)
+		if err != nil {
+			fmt.Printf("failed to delete firewalls for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Managed services keep subnets busy.  Always name the blockers; only remove them when asked to.  The scan
+	// needs read access to RDS, ElastiCache, EFS and Lambda, so a failure only matters when removing them.
+	subnetDeps, err := ListSubnetDependenciesForVpc(ctx, sess, vpcID)
+	if err != nil && !includeManagedDeps {
+		fmt.Printf("Warning: failed to scan subnet dependencies for VPC %s: %v\n", vpcID, err)
+	} else if err != nil {
+		results.Record("scan", "subnet dependencies", err)
+		fmt.Printf("failed to scan subnet dependencies for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	if len(subnetDeps) > 0 {
+		PrintSubnetDependencies(subnetDeps)
+		if includeManagedDeps && typeSelected(ResourceSubnet) {
+			err := DeleteSubnetDependencies(ctx, sess, vpcID, subnetDeps)
+			if err != nil {
+				fmt.Printf("failed to remove subnet dependencies for VPC %s: %v\n", vpcID, err)
+				errs = append(errs, err)
+				if !ignoreErrors {
+					return err
+				}
+			}
+		} else {
+			fmt.Println("Leaving managed-service subnet dependencies in place. Use the --include-managed-services flag to remove them.")
+		}
+	}
+
+	// Client VPN target network associations hold ENIs in the subnets and are billed hourly.
+	clientVpnEndpoints, err := ListClientVpnEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "Client VPN endpoints", err)
+		fmt.Printf("failed to list Client VPN endpoints for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	if len(clientVpnEndpoints) > 0 && typeSelected(ResourceSubnet) {
+		fmt.Printf("Tearing down %d Client VPN endpoints in VPC %s...\n", len(clientVpnEndpoints), vpcID)
+		err := DisassociateClientVpnTargetNetworks(ctx, sess, vpcID, clientVpnEndpoints)
+		if err == nil {
+			err = DeleteClientVpnEndpoints(ctx, sess, clientVpnEndpoints)
+		}
+		if err != nil {
+			fmt.Printf("failed to tear down Client VPN endpoints for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Resolver endpoints put ENIs into the subnets; rule and hosted zone associations pin the VPC.
+	resolverEndpoints, err := ListResolverEndpointsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		// Route 53 Resolver may be unavailable in the region or to the caller; as with the firewall listing, that
+		// is only a warning.
+		fmt.Printf("Warning: failed to list Resolver endpoints for VPC %s: %v\n", vpcID, err)
+	}
+
+	if len(resolverEndpoints) > 0 && typeSelected(ResourceSubnet) {
+		fmt.Printf("Deleting %d Resolver endpoints in VPC %s...\n", len(resolverEndpoints), vpcID)
+		err := DeleteResolverEndpoints(ctx, sess, resolverEndpoints)
+		if err != nil {
+			fmt.Printf("failed to delete Resolver endpoints for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	resolverRules, err := ListResolverRuleAssociationsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		fmt.Printf("Warning: failed to list Resolver rule associations for VPC %s: %v\n", vpcID, err)
+	}
+
+	if len(resolverRules) > 0 && typeSelected(ResourceVpc) {
+		fmt.Printf("Disassociating %d Resolver rules from VPC %s...\n", len(resolverRules), vpcID)
+		err := DisassociateResolverRules(ctx, sess, vpcID, resolverRules)
+		if err != nil {
+			fmt.Printf("failed to disassociate Resolver rules for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	hostedZones, err := ListHostedZonesForVpc(ctx, sess, vpcID)
+	if err != nil {
+		fmt.Printf("Warning: failed to list private hosted zones for VPC %s: %v\n", vpcID, err)
+	}
+
+	if len(hostedZones) > 0 && typeSelected(ResourceVpc) {
+		fmt.Printf("Disassociating %d private hosted zones from VPC %s...\n", len(hostedZones), vpcID)
+		err := DisassociateHostedZones(ctx, sess, vpcID, hostedZones, includeHostedZones)
+		if err != nil {
+			fmt.Printf("failed to disassociate private hosted zones for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	if len(vpcEndpoints) > 0 && typeSelected(ResourceVpcEndpoint) {
 		fmt.Printf("Deleting %d VPC endpoints in VPC %s...\n", len(vpcEndpoints), vpcID)
-		err := DeleteVpcEndpoints(sess, vpcEndpoints)
+		err := DeleteVpcEndpoints(ctx, sess, vpcEndpoints)
 		if err != nil {
-			fmt.Print("failed to delete VPC endpoints for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete VPC endpoints for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(natGateways) > 0 {
+	if len(natGateways) > 0 && typeSelected(ResourceNatGateway) {
 		fmt.Printf("Deleting %d NAT gateways in VPC %s...\n", len(natGateways), vpcID)
-		err := DeleteNatGateways(sess, natGateways)
+		deleted, err := DeleteNatGateways(ctx, sess, natGateways)
+		// A NAT gateway holds on to its Elastic IP until it is fully deleted.
+		if waitErr := WaitForNatGatewaysDeleted(ctx, sess, deleted); err == nil {
+			err = waitErr
+		}
 		if err != nil {
-			fmt.Print("failed to delete NAT gateways for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete NAT gateways for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(eips) > 0 {
+	if len(eips) > 0 && typeSelected(ResourceEip) {
 		fmt.Printf("Releasing %d Elastic IPs in VPC %s...\n", len(eips), vpcID)
-		err := ReleaseEips(sess, eips)
+		err := ReleaseEips(ctx, sess, eips)
+		if err != nil {
+			fmt.Printf("failed to release Elastic IPs for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	carrierGateways, err := ListCarrierGatewaysForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "carrier gateways", err)
+		fmt.Printf("failed to list carrier gateways for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	if len(carrierGateways) > 0 && typeSelected(ResourceVpc) {
+		fmt.Printf("Deleting %d carrier gateways in VPC %s...\n", len(carrierGateways), vpcID)
+		err := DeleteCarrierGateways(ctx, sess, carrierGateways)
 		if err != nil {
-			fmt.Print("failed to release Elastic IPs for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete carrier gateways for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
-		err = DeleteEips(sess, eips)
+	}
+
+	lgwAssociations, err := ListLocalGatewayRouteTableVpcAssociationsForVpc(ctx, sess, vpcID)
+	if err != nil {
+		results.Record("list", "local gateway route table associations", err)
+		fmt.Printf("failed to list local gateway route table associations for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	if len(lgwAssociations) > 0 && typeSelected(ResourceVpc) {
+		fmt.Printf("Deleting %d local gateway route table associations in VPC %s...\n", len(lgwAssociations), vpcID)
+		err := DeleteLocalGatewayRouteTableVpcAssociations(ctx, sess, lgwAssociations)
 		if err != nil {
-			fmt.Print("failed to delete Elastic IPs for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete local gateway route table associations for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(igws) > 0 {
+	if len(igws) > 0 && typeSelected(ResourceIgw) {
 		fmt.Printf("Detaching and deleting %d Internet gateways in VPC %s...\n", len(igws), vpcID)
-		err := DetachAndDeleteIgws(sess, igws)
+		err := DetachAndDeleteIgws(ctx, sess, igws)
 		if err != nil {
-			fmt.Print("failed to detach and delete Internet gateways for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to detach and delete Internet gateways for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(routeTables) > 0 {
+	if len(routeTables) > 0 && typeSelected(ResourceRouteTable) {
 		fmt.Printf("Deleting %d route tables in VPC %s...\n", len(routeTables), vpcID)
-		err := DeleteRouteTables(sess, routeTables)
+		err := DeleteRouteTables(ctx, sess, routeTables)
 		if err != nil {
-			fmt.Print("failed to delete route tables for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete route tables for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(sgs) > 0 {
+	if len(sgs) > 0 && typeSelected(ResourceSecurityGroup) {
 		fmt.Printf("Deleting %d security groups in VPC %s...\n", len(sgs), vpcID)
-		err := DeleteSgs(sess, sgs)
+		err := DeleteSgs(ctx, sess, sgs, defaultSgID)
+		if err != nil {
+			fmt.Printf("failed to delete security groups for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// A kept VPC gets its default NACL and security group back to the rules AWS created them with.  This comes
+	// before the prefix lists, which the default security group may reference.
+	if resettingDefaults() {
+		for _, nacl := range nacls {
+			if aws.BoolValue(nacl.IsDefault) {
+				err := ResetDefaultNacl(ctx, sess, vpc, nacl)
+				if err != nil {
+					fmt.Printf("failed to reset default network ACL for VPC %s: %v\n", vpcID, err)
+					errs = append(errs, err)
+					if !ignoreErrors {
+						return err
+					}
+				}
+			}
+		}
+		for _, sg := range sgs {
+			if isDefaultSg(sg, defaultSgID) {
+				err := ResetDefaultSg(ctx, sess, vpc, sg)
+				if err != nil {
+					fmt.Printf("failed to reset default security group for VPC %s: %v\n", vpcID, err)
+					errs = append(errs, err)
+					if !ignoreErrors {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	if len(prefixLists) > 0 && typeSelected(ResourceSecurityGroup) {
+		fmt.Printf("Deleting %d managed prefix lists used by VPC %s...\n", len(prefixLists), vpcID)
+		err := DeleteManagedPrefixLists(ctx, sess, prefixLists)
 		if err != nil {
-			fmt.Print("failed to delete security groups for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete managed prefix lists for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
-	if len(nacls) > 0 {
+	if len(nacls) > 0 && typeSelected(ResourceNacl) {
 		fmt.Printf("Deleting %d network ACLs in VPC %s...\n", len(nacls), vpcID)
-		err := DeleteNacls(sess, nacls)
+		err := DeleteNacls(ctx, sess, nacls)
+		if err != nil {
+			fmt.Printf("failed to delete network ACLs for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+
+	// Subnets shared through RAM may hold participants' resources; say so before DeleteSubnets fails.
+	subnetShares, err := ListSubnetSharesForVpc(ctx, sess, subnets)
+	if err != nil {
+		results.Record("list", "RAM shares", err)
+		fmt.Printf("failed to list RAM shares for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	if len(subnetShares) > 0 {
+		participantEnis, err := ListParticipantEnisForVpc(ctx, sess, vpcID)
 		if err != nil {
-			fmt.Print("failed to delete network ACLs for VPC %s: %v", vpcID, err)
+			results.Record("list", "participant network interfaces", err)
+			fmt.Printf("failed to list participant network interfaces for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
+		PrintSubnetShares(subnetShares, participantEnis)
+
+		if disassociateRamShares && typeSelected(ResourceSubnet) {
+			err := DisassociateSubnetShares(ctx, sess, subnetShares)
+			if err != nil {
+				fmt.Printf("failed to disassociate RAM shares for VPC %s: %v\n", vpcID, err)
+				errs = append(errs, err)
+				if !ignoreErrors {
+					return err
+				}
+			}
+		} else {
+			fmt.Println("Leaving subnets in their RAM resource shares. Use the --disassociate-ram-shares flag to remove them.")
+		}
 	}
 
 	// Delete all associated resources for the VPC.
-	if len(subnets) > 0 {
+	if len(subnets) > 0 && typeSelected(ResourceSubnet) {
 		fmt.Printf("Deleting %d subnets in VPC %s...\n", len(subnets), vpcID)
-		err := DeleteSubnets(sess, subnets)
+		err := DeleteSubnets(ctx, sess, subnets)
 		if err != nil {
-			fmt.Print("failed to delete subnets for VPC %s: %v", vpcID, err)
+			fmt.Printf("failed to delete subnets for VPC %s: %v\n", vpcID, err)
+			errs = append(errs, err)
 			if !ignoreErrors {
 				return err
 			}
 		}
 	}
 
+	// Unlike excluding the vpc resource type, --keep-vpc still detaches Resolver rules, hosted zones and the like.
+	if keepVpc || !typeSelected(ResourceVpc) {
+		fmt.Printf("Keeping VPC %s.\n", vpcID)
+		return errs.ErrorOrNil()
+	}
+
+	// Secondary and IPv6 CIDRs can only be disassociated once no subnet uses them.
+	ipamPools := ListIpamPoolsForVpc(ctx, sess, vpcID)
+	err = DisassociateVpcCidrBlocks(ctx, sess, vpc, ipamPools)
+	if err != nil {
+		fmt.Printf("failed to disassociate CIDR blocks for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
 	// Delete the VPC itself.
 	fmt.Printf("Deleting VPC %s...\n", vpcID)
-	err = DeleteVpcAndWait(sess, vpc)
+	err = DeleteVpcAndWait(ctx, sess, vpc, ipamPools)
 	if err != nil {
-		fmt.Print("failed to delete VPC %s: %v", vpcID, err)
+		fmt.Printf("failed to delete VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
 		if !ignoreErrors {
 			return err
 		}
 	}
 
-	return nil
+	// The DHCP options set outlives the VPC, so clean it up once nothing else references it.
+	err = DeleteDhcpOptionsIfUnused(ctx, sess, aws.StringValue(vpc.DhcpOptionsId))
+	if err != nil {
+		fmt.Printf("failed to delete DHCP options set for VPC %s: %v\n", vpcID, err)
+		errs = append(errs, err)
+		if !ignoreErrors {
+			return err
+		}
+	}
+
+	return errs.ErrorOrNil()
 }
 
-func ListNaclsForVpc(sess *session.Session, vpc *ec2.Vpc) ([]*ec2.NetworkAcl, error) {
+func ListNaclsForVpc(ctx context.Context, sess *session.Session, vpc *ec2.Vpc) ([]*ec2.NetworkAcl, error) {
 	svc := ec2.New(sess)
 	input := &ec2.DescribeNetworkAclsInput{
 		Filters: []*ec2.Filter{
@@ -337,74 +827,201 @@ func ListNaclsForVpc(sess *session.Session, vpc *ec2.Vpc) ([]*ec2.NetworkAcl, er
 			},
 		},
 	}
-	result, err := svc.DescribeNetworkAcls(input)
+	result, err := svc.DescribeNetworkAclsWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 	return result.NetworkAcls, nil
 }
 
-func DeleteNacls(sess *session.Session, nacls []*ec2.NetworkAcl) error {
+// DeleteNacls deletes the specified non-default network ACLs.  Subnets associated with a non-default ACL are
+// first moved to the VPC's default ACL, since AWS refuses to delete an ACL that is still associated.  Every ACL
+// is attempted; the failures are collected and returned together.
+func DeleteNacls(ctx context.Context, sess *session.Session, nacls []*ec2.NetworkAcl) error {
 	svc := ec2.New(sess)
 	fmt.Printf("Deleting %d network ACLs...\n", len(nacls))
 
+	var defaultNaclID *string
 	for _, nacl := range nacls {
-		fmt.Printf("Deleting network ACL %s...\n", *nacl.NetworkAclId)
-		input := &ec2.DeleteNetworkAclInput{
-			NetworkAclId: nacl.NetworkAclId,
+		if aws.BoolValue(nacl.IsDefault) {
+			defaultNaclID = nacl.NetworkAclId
 		}
-		_, err := svc.DeleteNetworkAcl(input)
-		if err != nil {
-			fmt.Printf("Error deleting network ACL %s: %v", *nacl.NetworkAclId, err)
-			if !ignoreErrors {
+	}
+
+	var errs MultiError
+	for _, nacl := range nacls {
+		if aws.BoolValue(nacl.IsDefault) {
+			continue
+		}
+
+		moved := true
+		for _, association := range nacl.Associations {
+			if defaultNaclID == nil {
+				break
+			}
+			fmt.Printf("Moving subnet %s from network ACL %s to the default network ACL...\n", aws.StringValue(association.SubnetId), *nacl.NetworkAclId)
+			err := forceAction("replace", "network ACL association", aws.StringValue(association.NetworkAclAssociationId), func() error {
+				_, err := svc.ReplaceNetworkAclAssociationWithContext(ctx, &ec2.ReplaceNetworkAclAssociationInput{
+					AssociationId: association.NetworkAclAssociationId,
+					NetworkAclId:  defaultNaclID,
+				})
 				return err
+			})
+			if err != nil {
+				fmt.Printf("Error replacing network ACL association: %v\n", err)
+				errs = append(errs, err)
+				moved = false
 			}
 		}
+		if !moved {
+			continue
+		}
+
+		fmt.Printf("Deleting network ACL %s...\n", *nacl.NetworkAclId)
+		err := forceAction("delete", "network ACL", aws.StringValue(nacl.NetworkAclId), func() error {
+			_, err := svc.DeleteNetworkAclWithContext(ctx, &ec2.DeleteNetworkAclInput{
+				NetworkAclId: nacl.NetworkAclId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting network ACL: %v\n", err)
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	return errs.ErrorOrNil()
 }
 
-func DeleteRouteTables(sess *session.Session, tables []*ec2.RouteTable) error {
+// DeleteRouteTables disassociates the subnet and gateway edge associations of the specified route tables and
+// deletes every table except the main one, which AWS only removes along with the VPC.  Every table is attempted;
+// the failures are collected and returned together.
+func DeleteRouteTables(ctx context.Context, sess *session.Session, tables []*ec2.RouteTable) error {
 	svc := ec2.New(sess)
 	fmt.Printf("Deleting %d route tables...\n", len(tables))
 
+	var errs MultiError
 	for _, table := range tables {
-		// do not delete the main route table.  Careful to check for nil.
-		if table.Associations != nil {
-			for _, association := range table.Associations {
-				if *association.Main {
-					fmt.Printf("Skipping main route table %s...\n", *table.RouteTableId)
-					continue
-				}
+		disassociated := true
+		for _, association := range table.Associations {
+			// The main association cannot be removed.  Careful to check for nil.
+			if aws.BoolValue(association.Main) {
+				continue
+			}
 
-				fmt.Printf("Disassociating route table %s...\n", *table.RouteTableId)
-				input := &ec2.DisassociateRouteTableInput{
+			target := aws.StringValue(association.SubnetId)
+			if association.GatewayId != nil {
+				target = aws.StringValue(association.GatewayId)
+			}
+			fmt.Printf("Disassociating route table %s from %s...\n", *table.RouteTableId, target)
+			err := forceAction("disassociate", "route table association", aws.StringValue(association.RouteTableAssociationId), func() error {
+				_, err := svc.DisassociateRouteTableWithContext(ctx, &ec2.DisassociateRouteTableInput{
 					AssociationId: association.RouteTableAssociationId,
-				}
-				_, err := svc.DisassociateRouteTable(input)
-				if err != nil {
-					fmt.Printf("Error disassociating route table %s: %v", *table.RouteTableId, err)
-					if !ignoreErrors {
-						return err
-					}
-				}
+				})
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Error disassociating route table %s: %v\n", *table.RouteTableId, err)
+				errs = append(errs, err)
+				disassociated = false
 			}
 		}
 
-		fmt.Printf("Deleting route table %s...\n", *table.RouteTableId)
-		input := &ec2.DeleteRouteTableInput{
-			RouteTableId: table.RouteTableId,
+		if isMainRouteTable(table) {
+			fmt.Printf("Skipping main route table %s...\n", *table.RouteTableId)
+			continue
+		}
+		if !disassociated {
+			continue
 		}
-		_, err := svc.DeleteRouteTable(input)
+
+		fmt.Printf("Deleting route table %s...\n", *table.RouteTableId)
+		err := forceAction("delete", "route table", aws.StringValue(table.RouteTableId), func() error {
+			_, err := svc.DeleteRouteTableWithContext(ctx, &ec2.DeleteRouteTableInput{
+				RouteTableId: table.RouteTableId,
+			})
+			return err
+		})
 		if err != nil {
-			fmt.Printf("Error deleting route table %s: %v", *table.RouteTableId, err)
+			fmt.Printf("Error deleting route table: %v\n", err)
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	return errs.ErrorOrNil()
+}
+
+// FlushMainRouteTableRoutes deletes every non-local route from the VPC's main route table, so that no route
+// still points at the NAT gateways, transit gateways, peering connections and endpoints that are about to go.
+// Routes propagated from a virtual private gateway, and the routes of gateway endpoints, cannot be deleted with
+// DeleteRoute and are left alone.  Every route is attempted; the failures are collected and returned together.
+func FlushMainRouteTableRoutes(ctx context.Context, sess *session.Session, tables []*ec2.RouteTable) error {
+	svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, table := range tables {
+		if !isMainRouteTable(table) {
+			continue
+		}
+
+		for _, route := range table.Routes {
+			if !isDeletableRoute(route) {
+				continue
+			}
+
+			fmt.Printf("Deleting route %s from main route table %s...\n", routeDestination(route), *table.RouteTableId)
+			err := forceAction("delete", "route", aws.StringValue(table.RouteTableId)+" "+routeDestination(route), func() error {
+				_, err := svc.DeleteRouteWithContext(ctx, &ec2.DeleteRouteInput{
+					RouteTableId:             table.RouteTableId,
+					DestinationCidrBlock:     route.DestinationCidrBlock,
+					DestinationIpv6CidrBlock: route.DestinationIpv6CidrBlock,
+					DestinationPrefixListId:  route.DestinationPrefixListId,
+				})
+				return err
+			})
+			if err != nil {
+				fmt.Printf("Error deleting route: %v\n", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// isMainRouteTable reports whether the route table is the VPC's main route table.
+func isMainRouteTable(table *ec2.RouteTable) bool {
+	for _, association := range table.Associations {
+		if aws.BoolValue(association.Main) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeletableRoute reports whether DeleteRoute can remove the route.  The local route, routes propagated from a
+// virtual private gateway, and the routes a gateway endpoint (vpce-...) adds to the tables it is associated with
+// are managed by AWS.
+func isDeletableRoute(route *ec2.Route) bool {
+	gatewayID := aws.StringValue(route.GatewayId)
+	if gatewayID == "local" || strings.HasPrefix(gatewayID, "vpce-") {
+		return false
+	}
+	return aws.StringValue(route.Origin) != ec2.RouteOriginEnableVgwRoutePropagation
+}
+
+// routeDestination returns the destination of a route, whichever form it takes.
+func routeDestination(route *ec2.Route) string {
+	switch {
+	case route.DestinationCidrBlock != nil:
+		return aws.StringValue(route.DestinationCidrBlock)
+	case route.DestinationIpv6CidrBlock != nil:
+		return aws.StringValue(route.DestinationIpv6CidrBlock)
+	default:
+		return aws.StringValue(route.DestinationPrefixListId)
+	}
 }
 
-func ListRouteTablesForVpc(sess *session.Session, vpc *ec2.Vpc) ([]*ec2.RouteTable, error) {
+func ListRouteTablesForVpc(ctx context.Context, sess *session.Session, vpc *ec2.Vpc) ([]*ec2.RouteTable, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.DescribeRouteTablesInput{
@@ -416,7 +1033,7 @@ func ListRouteTablesForVpc(sess *session.Session, vpc *ec2.Vpc) ([]*ec2.RouteTab
 		},
 	}
 
-	result, err := svc.DescribeRouteTables(input)
+	result, err := svc.DescribeRouteTablesWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -424,26 +1041,44 @@ func ListRouteTablesForVpc(sess *session.Session, vpc *ec2.Vpc) ([]*ec2.RouteTab
 	return result.RouteTables, nil
 }
 
-func DeleteSgs(sess *session.Session, sgs []*ec2.SecurityGroup) error {
+// DeleteSgs revokes the rules that reference other groups in the set, then deletes every non-default group.
+// Every group is attempted; the failures are collected and returned together.
+func DeleteSgs(ctx context.Context, sess *session.Session, sgs []*ec2.SecurityGroup, defaultSgID string) error {
 	fmt.Println("Deleting security groups...")
 	svc := ec2.New(sess)
+
+	var errs MultiError
+	err := RevokeSgCrossReferences(ctx, sess, sgs)
+	if err != nil {
+		fmt.Println("Error revoking security group cross-references:", err)
+		errs = append(errs, err)
+	}
+
 	for _, sg := range sgs {
-		if *sg.GroupName == "default" {
+		if isDefaultSg(sg, defaultSgID) {
 			continue
 		}
-		input := &ec2.DeleteSecurityGroupInput{
-			GroupId: sg.GroupId,
-		}
-		_, err := svc.DeleteSecurityGroup(input)
-		if err != nil {
+		fmt.Printf("Deleting security group %s (%s)...\n", aws.StringValue(sg.GroupId), aws.StringValue(sg.GroupName))
+		err := forceAction("delete", "security group", aws.StringValue(sg.GroupId), func() error {
+			_, err := svc.DeleteSecurityGroupWithContext(ctx, &ec2.DeleteSecurityGroupInput{
+				GroupId: sg.GroupId,
+			})
 			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting security group: %v\n", err)
+			errs = append(errs, err)
 		}
 	}
+
+	if len(errs) > 0 {
+		return errs
+	}
 	fmt.Println("Done deleting security groups.")
 	return nil
 }
 
-func ListSgsForVpc(sess *session.Session, id string) ([]*ec2.SecurityGroup, error) {
+func ListSgsForVpc(ctx context.Context, sess *session.Session, id string) ([]*ec2.SecurityGroup, error) {
 	// Create an EC2 service client.
 	svc := ec2.New(sess)
 
@@ -458,7 +1093,7 @@ func ListSgsForVpc(sess *session.Session, id string) ([]*ec2.SecurityGroup, erro
 	}
 
 	// Retrieve the security groups.
-	result, err := svc.DescribeSecurityGroups(input)
+	result, err := svc.DescribeSecurityGroupsWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -466,188 +1101,268 @@ func ListSgsForVpc(sess *session.Session, id string) ([]*ec2.SecurityGroup, erro
 	return result.SecurityGroups, nil
 }
 
-// DeleteEips deletes the specified Elastic IPs.
-func DeleteEips(sess *session.Session, eips []*ec2.Address) error {
-	svc := ec2.New(sess)
-	for _, eip := range eips {
-		// Initialize the input parameters.
-		input := &ec2.ReleaseAddressInput{
-			PublicIp: eip.PublicIp,
-		}
-
-		// Release the Elastic IPs.
-		_, err := svc.ReleaseAddress(input)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// DeleteSubnets deletes the specified subnets.
-func DeleteSubnets(sess *session.Session, subnets []*ec2.Subnet) error {
+// DeleteSubnets deletes the specified subnets.  Every subnet is attempted; the failures are collected and
+// returned together.
+func DeleteSubnets(ctx context.Context, sess *session.Session, subnets []*ec2.Subnet) error {
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
 	// Delete each subnet.
+	var errs MultiError
 	for _, subnet := range subnets {
 		// Get the name of the subnet.
 		name := getNameTag(subnet.Tags)
 
 		// Delete the subnet.
 		fmt.Printf("Deleting subnet %s (%s)...\n", aws.StringValue(subnet.SubnetId), name)
-		_, err := ec2Svc.DeleteSubnet(&ec2.DeleteSubnetInput{
-			SubnetId: subnet.SubnetId,
+		err := forceAction("delete", "subnet", aws.StringValue(subnet.SubnetId), func() error {
+			_, err := ec2Svc.DeleteSubnetWithContext(ctx, &ec2.DeleteSubnetInput{
+				SubnetId: subnet.SubnetId,
+			})
+			return err
 		})
 		if err != nil {
-			return err
+			fmt.Printf("Error deleting subnet: %v\n", err)
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
-// DeleteVpcEndpoints deletes the specified VPC endpoints.
-func DeleteVpcEndpoints(sess *session.Session, vpcEndpoints []*ec2.VpcEndpoint) error {
+// DeleteVpcEndpoints deletes the specified VPC endpoints.  Every endpoint is attempted; the failures are collected
+// and returned together.
+func DeleteVpcEndpoints(ctx context.Context, sess *session.Session, vpcEndpoints []*ec2.VpcEndpoint) error {
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
 	// Delete each VPC endpoint.
+	var errs MultiError
+	var gwlbEndpointIds []*string
 	for _, vpcEndpoint := range vpcEndpoints {
+		// Endpoints owned by another service (e.g. Network Firewall) go away with their owner.
+		if aws.BoolValue(vpcEndpoint.RequesterManaged) {
+			fmt.Printf("Skipping requester-managed VPC endpoint %s...\n", aws.StringValue(vpcEndpoint.VpcEndpointId))
+			continue
+		}
 		fmt.Printf("Deleting VPC endpoint %s...\n", aws.StringValue(vpcEndpoint.VpcEndpointId))
 
-		if forceFlag {
-			_, err := ec2Svc.DeleteVpcEndpoints(&ec2.DeleteVpcEndpointsInput{
+		err := forceAction("delete", "VPC endpoint", aws.StringValue(vpcEndpoint.VpcEndpointId), func() error {
+			result, err := ec2Svc.DeleteVpcEndpointsWithContext(ctx, &ec2.DeleteVpcEndpointsInput{
 				VpcEndpointIds: []*string{vpcEndpoint.VpcEndpointId},
 			})
 			if err != nil {
 				return err
 			}
-		} else {
-			fmt.Println("Skipping VPC endpoint deletion. Use the --force flag to force deletion.")
+			return unsuccessfulItemsError(result.Unsuccessful)
+		})
+		if err != nil {
+			fmt.Printf("Error deleting VPC endpoint: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		if forceFlag && aws.StringValue(vpcEndpoint.VpcEndpointType) == ec2.VpcEndpointTypeGatewayLoadBalancer {
+			gwlbEndpointIds = append(gwlbEndpointIds, vpcEndpoint.VpcEndpointId)
 		}
 	}
 
-	return nil
+	// Route tables point at Gateway Load Balancer endpoints, so make sure they are gone before the routes are.
+	if len(gwlbEndpointIds) > 0 {
+		fmt.Println("Waiting for Gateway Load Balancer endpoints to be deleted...")
+		err := WaitForVpcEndpointsDeleted(ctx, sess, gwlbEndpointIds)
+		if err != nil {
+			results.Record("wait for", "Gateway Load Balancer endpoints", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// WaitForVpcEndpointsDeleted polls the specified VPC endpoints until they are all deleted.
+func WaitForVpcEndpointsDeleted(ctx context.Context, sess *session.Session, vpcEndpointIds []*string) error {
+	ec2Svc := ec2.New(sess)
+	deadline := time.Now().Add(vpcEndpointDeleteTimeout)
+	for {
+		result, err := ec2Svc.DescribeVpcEndpointsWithContext(ctx, &ec2.DescribeVpcEndpointsInput{
+			Filters: []*ec2.Filter{
+				{
+					Name:   aws.String("vpc-endpoint-id"),
+					Values: vpcEndpointIds,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		remaining := 0
+		for _, vpcEndpoint := range result.VpcEndpoints {
+			if !strings.EqualFold(aws.StringValue(vpcEndpoint.State), "deleted") {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %d VPC endpoints to be deleted", remaining)
+		}
+		if err := sleepContext(ctx, 10*time.Second); err != nil {
+			return err
+		}
+	}
 }
 
-// DeleteNatGateways deletes the specified NAT gateways and waits for them to be deleted.
-func DeleteNatGateways(sess *session.Session, natGateways []*ec2.NatGateway) error {
+// DeleteNatGateways deletes the specified NAT gateways.  It returns the NAT gateways it deleted.  Every gateway is
+// attempted; the failures are collected and returned together.
+func DeleteNatGateways(ctx context.Context, sess *session.Session, natGateways []*ec2.NatGateway) ([]*ec2.NatGateway, error) {
 	fmt.Println("Deleting NAT gateways...")
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
 	// Delete each NAT gateway.
+	var errs MultiError
+	var deleted []*ec2.NatGateway
 	for _, natGw := range natGateways {
 		fmt.Printf("Deleting NAT gateway %s...\n", aws.StringValue(natGw.NatGatewayId))
-
-		if forceFlag {
-			_, err := ec2Svc.DeleteNatGateway(&ec2.DeleteNatGatewayInput{
+		err := forceAction("delete", "NAT gateway", aws.StringValue(natGw.NatGatewayId), func() error {
+			_, err := ec2Svc.DeleteNatGatewayWithContext(ctx, &ec2.DeleteNatGatewayInput{
 				NatGatewayId: natGw.NatGatewayId,
 			})
 			if err != nil {
 				return err
 			}
-			// Wait for the NAT gateways to be deleted.
-			//fmt.Println("Waiting for NAT gateways to be deleted...")
-			//err = ec2Svc.WaitUntilNatGatewayDeleted(&ec2.DescribeNatGatewaysInput{})
-			//if err != nil {
-			//	return err
-			//}
-		} else {
-			fmt.Println("Skipping NAT gateway deletion. Use the --force flag to force deletion.")
+			deleted = append(deleted, natGw)
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error deleting NAT gateway: %v\n", err)
+			errs = append(errs, err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return deleted, errs
+	}
 	fmt.Println("NAT gateways deleted.")
-	return nil
+	return deleted, nil
 }
 
-// ReleaseEips releases the specified EIPs.
-func ReleaseEips(sess *session.Session, eips []*ec2.Address) error {
+// ReleaseEips disassociates the specified EIPs from their network interface, if any, and releases them.  An
+// association that is already gone, such as that of a deleted NAT gateway, is not an error.  Every address is
+// attempted; the failures are collected and returned together.
+func ReleaseEips(ctx context.Context, sess *session.Session, eips []*ec2.Address) error {
 	fmt.Println("Releasing EIPs...")
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
 	// Release each EIP.
+	var errs MultiError
 	for _, eip := range eips {
 		fmt.Printf("Releasing EIP %s...\n", aws.StringValue(eip.PublicIp))
-
-		if forceFlag {
-			_, err := ec2Svc.ReleaseAddress(&ec2.ReleaseAddressInput{
-				PublicIp: eip.PublicIp,
-			})
-			if err != nil {
-				return err
+		err := forceAction("release", "Elastic IP", aws.StringValue(eip.PublicIp), func() error {
+			if eip.AssociationId != nil {
+				_, err := ec2Svc.DisassociateAddressWithContext(ctx, &ec2.DisassociateAddressInput{
+					AssociationId: eip.AssociationId,
+				})
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidAssociationID.NotFound" {
+					err = nil
+				}
+				if err != nil {
+					return err
+				}
 			}
-		} else {
-			fmt.Println("Skipping EIP release. Use the --force flag to force release.")
+			_, err := ec2Svc.ReleaseAddressWithContext(ctx, &ec2.ReleaseAddressInput{
+				AllocationId: eip.AllocationId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error releasing EIP: %v\n", err)
+			errs = append(errs, err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	fmt.Println("EIPs released.")
 	return nil
 }
 
-// DetachAndDeleteIgws detaches and deletes the specified Internet gateways.
-func DetachAndDeleteIgws(sess *session.Session, igws []*ec2.InternetGateway) error {
+// DetachAndDeleteIgws detaches and deletes the specified Internet gateways.  Every gateway is attempted; the
+// failures are collected and returned together.
+func DetachAndDeleteIgws(ctx context.Context, sess *session.Session, igws []*ec2.InternetGateway) error {
 	fmt.Println("Detaching and deleting Internet gateways...")
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
 	// Detach and delete each Internet gateway.
+	var errs MultiError
 	for _, igw := range igws {
+		igwID := aws.StringValue(igw.InternetGatewayId)
 		// Get the name of the Internet gateway.
 		name := getNameTag(igw.Tags)
 
 		// Detach the Internet gateway from its VPC.
 		vpcId := aws.StringValue(igw.Attachments[0].VpcId)
-		fmt.Printf("Detaching Internet gateway %s (%s) from VPC %s...\n", aws.StringValue(igw.InternetGatewayId), name, vpcId)
-
-		if forceFlag {
-			_, err := ec2Svc.DetachInternetGateway(&ec2.DetachInternetGatewayInput{
+		fmt.Printf("Detaching Internet gateway %s (%s) from VPC %s...\n", igwID, name, vpcId)
+		err := forceAction("detach", "Internet gateway", igwID, func() error {
+			_, err := ec2Svc.DetachInternetGatewayWithContext(ctx, &ec2.DetachInternetGatewayInput{
 				InternetGatewayId: igw.InternetGatewayId,
 				VpcId:             aws.String(vpcId),
 			})
 			if err != nil {
 				return err
 			}
-		} else {
-			fmt.Println("Skipping Internet gateway detachment. Use the --force flag to force detachment.")
-		}
-
-		// Wait for the Internet gateway to be detached.
-		if forceFlag {
+			// Wait for the Internet gateway to be detached.
 			fmt.Println("Waiting for Internet gateway to be detached...")
-			err := ec2Svc.WaitUntilInternetGatewayExists(&ec2.DescribeInternetGatewaysInput{ // TODO WaitUntilInternetGatewayDetached is not available in the SDK.
+			return ec2Svc.WaitUntilInternetGatewayExistsWithContext(ctx, &ec2.DescribeInternetGatewaysInput{ // TODO WaitUntilInternetGatewayDetached is not available in the SDK.
 				InternetGatewayIds: []*string{igw.InternetGatewayId},
 			})
-			if err != nil {
-				return err
-			}
+		})
+		if err != nil {
+			fmt.Printf("Error detaching Internet gateway: %v\n", err)
+			errs = append(errs, err)
+			continue
 		}
 
 		// Delete the Internet gateway.
-		fmt.Printf("Deleting Internet gateway %s (%s)...\n", aws.StringValue(igw.InternetGatewayId), name)
-
-		if forceFlag {
-			_, err := ec2Svc.DeleteInternetGateway(&ec2.DeleteInternetGatewayInput{
+		fmt.Printf("Deleting Internet gateway %s (%s)...\n", igwID, name)
+		err = forceAction("delete", "Internet gateway", igwID, func() error {
+			_, err := ec2Svc.DeleteInternetGatewayWithContext(ctx, &ec2.DeleteInternetGatewayInput{
 				InternetGatewayId: igw.InternetGatewayId,
 			})
-			if err != nil {
-				return err
-			}
-		} else {
-			fmt.Println("Skipping Internet gateway deletion. Use the --force flag to force deletion.")
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting Internet gateway: %v\n", err)
+			errs = append(errs, err)
 		}
-
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
 	fmt.Println("Internet gateways detached and deleted.")
 	return nil
 }
 
+// unsuccessfulItemsError turns the Unsuccessful items of a batch EC2 call into an error, or nil if there are none.
+func unsuccessfulItemsError(items []*ec2.UnsuccessfulItem) error {
+	var failures []string
+	for _, item := range items {
+		if item.Error != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", aws.StringValue(item.ResourceId), aws.StringValue(item.Error.Message)))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
 // getNameTag returns the value of the "Name" tag for the specified resource, or an empty string if the tag is not present.
 func getNameTag(tags []*ec2.Tag) string {
 	for _, tag := range tags {
@@ -658,9 +1373,8 @@ func getNameTag(tags []*ec2.Tag) string {
 	return ""
 }
 
-// DeleteVpcAndWait deletes the specified VPC and waits for it to be deleted.
-func DeleteVpcAndWait(sess *session.Session, vpc *ec2.Vpc) error {
-	fmt.Println("Deleting VPC...")
+// DeleteVpcAndWait deletes the specified VPC and, once it is gone, reports the IPAM release of its primary CIDR.
+func DeleteVpcAndWait(ctx context.Context, sess *session.Session, vpc *ec2.Vpc, ipamPools map[string]string) error {
 	// Create a new EC2 client using the provided session.
 	ec2Svc := ec2.New(sess)
 
@@ -669,29 +1383,66 @@ func DeleteVpcAndWait(sess *session.Session, vpc *ec2.Vpc) error {
 
 	// Delete the VPC.
 	fmt.Printf("Deleting VPC %s (%s)...\n", aws.StringValue(vpc.VpcId), name)
-	if forceFlag {
-		_, err := ec2Svc.DeleteVpc(&ec2.DeleteVpcInput{
+	return forceAction("delete", "VPC", aws.StringValue(vpc.VpcId), func() error {
+		_, err := ec2Svc.DeleteVpcWithContext(ctx, &ec2.DeleteVpcInput{
 			VpcId: vpc.VpcId,
 		})
 		if err != nil {
-			fmt.Println("Error deleting VPC:", err)
-			if !ignoreErrors {
-				return err
-			}
+			return err
 		}
-		// Wait for the VPC to be deleted.
-		fmt.Println("Waiting for VPC to be deleted...")
-		//err = ec2Svc.WaitUntilVpc(&ec2.DescribeVpcsInput{
-		//	VpcIds: []*string{vpc.VpcId},
-		//})
-		//if err != nil {
-		//	return err
-		//}
-	} else {
-		fmt.Println("Skipping VPC deletion. Use the --force flag to force deletion.")
+		reportIpamRelease(aws.StringValue(vpc.CidrBlock), ipamPools)
+		return nil
+	})
+}
+
+// ListNetworkInterfacesForVpc lists all network interfaces for the specified VPC ID in the specified session.
+func ListNetworkInterfacesForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.NetworkInterface, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeNetworkInterfacesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
 	}
 
-	fmt.Println("VPC deleted, I think.")
+	result, err := svc.DescribeNetworkInterfacesWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces for VPC %s: %v", vpcID, err)
+	}
 
-	return nil
+	return result.NetworkInterfaces, nil
+}
+
+// WaitForNetworkInterfacesReleased polls the network interfaces in the VPC until none of them satisfy match,
+// or until the timeout elapses.  AWS releases requester-managed interfaces asynchronously after the owning
+// resource is deleted, and subnets and security groups cannot be deleted while they remain.
+func WaitForNetworkInterfacesReleased(ctx context.Context, sess *session.Session, vpcID string, match func(*ec2.NetworkInterface) bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		enis, err := ListNetworkInterfacesForVpc(ctx, sess, vpcID)
+		if err != nil {
+			return err
+		}
+
+		var remaining []string
+		for _, eni := range enis {
+			if match(eni) {
+				remaining = append(remaining, aws.StringValue(eni.NetworkInterfaceId))
+			}
+		}
+		if len(remaining) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for network interfaces to be released: %v", remaining)
+		}
+
+		fmt.Printf("Waiting for %d network interfaces to be released...\n", len(remaining))
+		if err := sleepContext(ctx, 15*time.Second); err != nil {
+			return err
+		}
+	}
 }
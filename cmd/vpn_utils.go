@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// ListTransitGatewayVpcAttachmentsForVpc lists the transit gateway attachments of the specified VPC that are not
+// already deleted or being deleted.
+func ListTransitGatewayVpcAttachmentsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.TransitGatewayVpcAttachment, error) {
+	svc := ec2.New(sess)
+
+	input := &ec2.DescribeTransitGatewayVpcAttachmentsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+			{
+				Name: aws.String("state"),
+				Values: aws.StringSlice([]string{
+					ec2.TransitGatewayAttachmentStateAvailable,
+					ec2.TransitGatewayAttachmentStatePending,
+					ec2.TransitGatewayAttachmentStatePendingAcceptance,
+					ec2.TransitGatewayAttachmentStateModifying,
+					ec2.TransitGatewayAttachmentStateFailed,
+				}),
+			},
+		},
+	}
+
+	result, err := svc.DescribeTransitGatewayVpcAttachmentsWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transit gateway attachments for VPC %s: %v", vpcID, err)
+	}
+
+	return result.TransitGatewayVpcAttachments, nil
+}
+
+// DeleteTransitGatewayVpcAttachments deletes the specified transit gateway VPC attachments.
+func DeleteTransitGatewayVpcAttachments(ctx context.Context, sess *session.Session, attachments []*ec2.TransitGatewayVpcAttachment) error {
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, attachment := range attachments {
+		fmt.Printf("Deleting transit gateway attachment %s (%s)...\n", aws.StringValue(attachment.TransitGatewayAttachmentId), aws.StringValue(attachment.TransitGatewayId))
+		err := forceAction("delete", "transit gateway attachment", aws.StringValue(attachment.TransitGatewayAttachmentId), func() error {
+			_, err := ec2Svc.DeleteTransitGatewayVpcAttachmentWithContext(ctx, &ec2.DeleteTransitGatewayVpcAttachmentInput{
+				TransitGatewayAttachmentId: attachment.TransitGatewayAttachmentId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting transit gateway attachment: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// ListVpnConnectionsForVpc lists the Site-to-Site VPN connections that terminate on a virtual private gateway
+// attached to the specified VPC.  VPN connections on a transit gateway are not tied to one VPC and are not listed.
+func ListVpnConnectionsForVpc(ctx context.Context, sess *session.Session, vpcID string) ([]*ec2.VpnConnection, error) {
+	svc := ec2.New(sess)
+
+	vgws, err := svc.DescribeVpnGatewaysWithContext(ctx, &ec2.DescribeVpnGatewaysInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("attachment.vpc-id"),
+				Values: []*string{aws.String(vpcID)},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual private gateways for VPC %s: %v", vpcID, err)
+	}
+	if len(vgws.VpnGateways) == 0 {
+		return nil, nil
+	}
+
+	var vgwIDs []*string
+	for _, vgw := range vgws.VpnGateways {
+		vgwIDs = append(vgwIDs, vgw.VpnGatewayId)
+	}
+	result, err := svc.DescribeVpnConnectionsWithContext(ctx, &ec2.DescribeVpnConnectionsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("vpn-gateway-id"),
+				Values: vgwIDs,
+			},
+			{
+				Name:   aws.String("state"),
+				Values: aws.StringSlice([]string{ec2.VpnStatePending, ec2.VpnStateAvailable}),
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VPN connections for VPC %s: %v", vpcID, err)
+	}
+
+	return result.VpnConnections, nil
+}
+
+// DeleteVpnConnections deletes the specified Site-to-Site VPN connections.
+func DeleteVpnConnections(ctx context.Context, sess *session.Session, vpnConnections []*ec2.VpnConnection) error {
+	ec2Svc := ec2.New(sess)
+
+	var errs MultiError
+	for _, vpnConnection := range vpnConnections {
+		fmt.Printf("Deleting VPN connection %s (%s)...\n", aws.StringValue(vpnConnection.VpnConnectionId), getNameTag(vpnConnection.Tags))
+		err := forceAction("delete", "VPN connection", aws.StringValue(vpnConnection.VpnConnectionId), func() error {
+			_, err := ec2Svc.DeleteVpnConnectionWithContext(ctx, &ec2.DeleteVpnConnectionInput{
+				VpnConnectionId: vpnConnection.VpnConnectionId,
+			})
+			return err
+		})
+		if err != nil {
+			fmt.Printf("Error deleting VPN connection: %v\n", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errs.ErrorOrNil()
+}